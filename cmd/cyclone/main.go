@@ -1,10 +1,15 @@
 package main
 
 import (
+	"context"
 	"cyclone/internal/bot"
 	"cyclone/internal/config"
+	"errors"
 	"log"
 	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
 )
 
 func main() {
@@ -14,8 +19,8 @@ func main() {
 		log.Fatalf("Failed to load configuration: %v", err)
 	}
 
-	// Create configuration provider using the config
-	configProvider, err := config.NewSupabaseProvider(cfg)
+	// Create the configuration provider selected by CONFIG_BACKEND
+	configProvider, err := config.NewConfigProvider(cfg)
 	if err != nil {
 		log.Fatalf("Failed to create configuration provider: %v", err)
 	}
@@ -26,8 +31,38 @@ func main() {
 		log.Fatalf("Failed to create bot: %v", err)
 	}
 
+	// Stop on SIGINT or SIGTERM, e.g. from a deploy
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	// Run queued reviews in the background until shutdown
+	jobsDone := make(chan struct{})
+	go func() {
+		defer close(jobsDone)
+		cycloneBot.RunJobs(ctx)
+	}()
+
 	// Setup routes and start server
 	cycloneBot.SetupRoutes()
-	log.Printf("Starting server on port %s", cfg.Port)
-	log.Fatal(http.ListenAndServe(":"+cfg.Port, nil))
+	server := &http.Server{Addr: ":" + cfg.Port}
+	go func() {
+		log.Printf("Starting server on port %s", cfg.Port)
+		if err := server.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			log.Fatalf("Server failed: %v", err)
+		}
+	}()
+
+	<-ctx.Done()
+	stop()
+	log.Printf("Shutting down")
+
+	// Answer the webhooks in flight, then wait for the running jobs to be canceled and requeued
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), config.SHUTDOWN_TIMEOUT)
+	defer cancel()
+	if err := server.Shutdown(shutdownCtx); err != nil {
+		log.Printf("Error shutting down server: %v", err)
+	}
+	<-jobsDone
+
+	log.Printf("Shutdown complete")
 }
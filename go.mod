@@ -8,6 +8,8 @@ require (
 	github.com/golang-jwt/jwt/v4 v4.5.2
 	github.com/google/go-github/v57 v57.0.0
 	golang.org/x/oauth2 v0.30.0
+	golang.org/x/sync v0.10.0
+	gopkg.in/yaml.v3 v3.0.1
 )
 
 require github.com/google/go-querystring v1.1.0 // indirect
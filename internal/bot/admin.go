@@ -0,0 +1,138 @@
+package bot
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"cyclone/internal/config"
+	"cyclone/internal/jobs"
+)
+
+// authorizeAdmin checks the bearer token of an admin request, answering it if it fails
+func (bot *CycloneBot) authorizeAdmin(w http.ResponseWriter, r *http.Request) bool {
+	token := strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+	if bot.config.AdminToken == "" || subtle.ConstantTimeCompare([]byte(token), []byte(bot.config.AdminToken)) != 1 {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return false
+	}
+	return true
+}
+
+// handleInvalidateConfig drops cached repository configurations after they were changed,
+// e.g. in the dashboard. The org and repo query parameters narrow it down, without them
+// the whole cache is dropped.
+func (bot *CycloneBot) handleInvalidateConfig(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if !bot.authorizeAdmin(w, r) {
+		return
+	}
+
+	org, repo := r.URL.Query().Get("org"), r.URL.Query().Get("repo")
+	if repo != "" && org == "" {
+		http.Error(w, "repo requires org", http.StatusBadRequest)
+		return
+	}
+
+	dropped := 0
+	if cache, ok := bot.configProvider.(config.CacheInvalidator); ok {
+		dropped = cache.Invalidate(org, repo)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(map[string]int{"invalidated": dropped}); err != nil {
+		log.Printf("Error writing admin response: %v", err)
+	}
+}
+
+// handleListJobs lists the jobs in a status, the dead letters unless the status query
+// parameter asks for queued or running jobs. The limit query parameter caps the number
+// of jobs listed.
+func (bot *CycloneBot) handleListJobs(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if !bot.authorizeAdmin(w, r) {
+		return
+	}
+
+	status := jobs.Status(r.URL.Query().Get("status"))
+	switch status {
+	case "":
+		status = jobs.StatusDead
+	case jobs.StatusQueued, jobs.StatusRunning, jobs.StatusDead:
+	default:
+		http.Error(w, "status must be queued, running or dead", http.StatusBadRequest)
+		return
+	}
+
+	limit := 100
+	if value := r.URL.Query().Get("limit"); value != "" {
+		parsed, err := strconv.Atoi(value)
+		if err != nil || parsed < 1 {
+			http.Error(w, "limit must be a number greater than zero", http.StatusBadRequest)
+			return
+		}
+		limit = min(parsed, 1000)
+	}
+
+	list, err := bot.jobs.Queue().List(r.Context(), status, limit)
+	if err != nil {
+		log.Printf("Error listing jobs: %v", err)
+		http.Error(w, "Failed to list jobs", http.StatusInternalServerError)
+		return
+	}
+	if list == nil {
+		list = []jobs.Job{}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(map[string]any{"jobs": list}); err != nil {
+		log.Printf("Error writing admin response: %v", err)
+	}
+}
+
+// handleRequeueJob queues the dead job with the id query parameter again, with fresh attempts
+func (bot *CycloneBot) handleRequeueJob(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if !bot.authorizeAdmin(w, r) {
+		return
+	}
+
+	id := r.URL.Query().Get("id")
+	if id == "" {
+		http.Error(w, "id is required", http.StatusBadRequest)
+		return
+	}
+
+	err := bot.jobs.Queue().Requeue(r.Context(), id)
+	if errors.Is(err, jobs.ErrNotFound) {
+		http.Error(w, "No dead job with this id", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		log.Printf("Error requeueing job %s: %v", id, err)
+		http.Error(w, "Failed to requeue job", http.StatusInternalServerError)
+		return
+	}
+	log.Printf("Requeued job %s", id)
+
+	// Wake the workers up, the job is due now
+	bot.jobs.Notify()
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(map[string]string{"requeued": id}); err != nil {
+		log.Printf("Error writing admin response: %v", err)
+	}
+}
@@ -0,0 +1,59 @@
+package bot
+
+import (
+	"context"
+	"log"
+
+	"github.com/google/go-github/v57/github"
+
+	"cyclone/internal/config"
+	"cyclone/internal/review"
+)
+
+// reviewCheck is the check run reporting a Cyclone review on a PR's head commit.
+// A nil reviewCheck is valid and does nothing, so reviews still work when the check
+// run can't be created (e.g. a personal access token without the checks permission).
+type reviewCheck struct {
+	githubClient *review.GitHubClient
+	owner        string
+	repo         string
+	id           int64
+}
+
+// startReviewCheck creates an in_progress check run on the PR's head commit
+func startReviewCheck(ctx context.Context, githubClient *review.GitHubClient, repo *github.Repository, pr *github.PullRequest) *reviewCheck {
+	owner := repo.GetOwner().GetLogin()
+	repoName := repo.GetName()
+
+	id, err := githubClient.CreateCheckRun(ctx, owner, repoName, pr.GetHead().GetSHA())
+	if err != nil {
+		log.Printf("Error creating check run for PR #%d: %v", pr.GetNumber(), err)
+		return nil
+	}
+
+	return &reviewCheck{githubClient: githubClient, owner: owner, repo: repoName, id: id}
+}
+
+// completeWithReview completes the check run with the review's comments as annotations and a
+// conclusion following the repository's check policy. The conclusion is decided on result,
+// which should still contain the comments that could not be anchored in the diff, while the
+// annotations are taken from anchored, the review as posted.
+func (c *reviewCheck) completeWithReview(ctx context.Context, result, anchored review.ReviewResult, policy config.CheckPolicy) {
+	conclusion := review.CheckConclusion(result, policy)
+	c.complete(ctx, conclusion, review.CheckTitle(result), review.CheckSummary(anchored.Summary), review.CheckAnnotations(anchored))
+}
+
+// skip completes the check run as neutral, e.g. when the PR is too large to review
+func (c *reviewCheck) skip(ctx context.Context, title, summary string) {
+	c.complete(ctx, review.ConclusionNeutral, title, summary, nil)
+}
+
+func (c *reviewCheck) complete(ctx context.Context, conclusion, title, summary string, annotations []*github.CheckRunAnnotation) {
+	if c == nil {
+		return
+	}
+
+	if err := c.githubClient.CompleteCheckRun(ctx, c.owner, c.repo, c.id, conclusion, title, summary, annotations); err != nil {
+		log.Printf("Error completing check run %d: %v", c.id, err)
+	}
+}
@@ -0,0 +1,227 @@
+package bot
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strings"
+
+	"github.com/google/go-github/v57/github"
+
+	"cyclone/internal/review"
+)
+
+// pausedLabel marks PRs on which automatic reviews are paused
+const pausedLabel = "cyclone:paused"
+
+const commandPrefix = "/cyclone"
+
+const commandHelp = `## 🌪️ Cyclone Commands
+
+| Command | Description |
+|---|---|
+| ` + "`/cyclone review`" + ` | Run a full review of the PR |
+| ` + "`/cyclone review path/to/file.go`" + ` | Review a single file |
+| ` + "`/cyclone explain <question>`" + ` | Ask a question about the changes |
+| ` + "`/cyclone pause`" + ` | Stop automatic reviews on this PR (alias: ` + "`ignore`" + `) |
+| ` + "`/cyclone resume`" + ` | Resume automatic reviews on this PR |
+| ` + "`/cyclone help`" + ` | Show this message |
+
+*Commands are accepted from users with write access to the repository.* 🌪️`
+
+// command is a parsed /cyclone slash command
+type command struct {
+	verb string
+	args string
+}
+
+// commandContext describes where a command was issued
+type commandContext struct {
+	repo            *github.Repository
+	prNumber        int
+	installationID  int64
+	commentID       int64
+	threadID        int64 // root comment of the review thread, replies must target it
+	author          string
+	isReviewComment bool // issued in a line-specific review thread rather than the PR conversation
+}
+
+// parseCommand extracts a /cyclone command from a comment body. Everything after the
+// verb (including following lines) is treated as the command's arguments.
+func parseCommand(body string) (*command, bool) {
+	lines := strings.Split(body, "\n")
+	for i, line := range lines {
+		line = strings.TrimSpace(line)
+		if !strings.HasPrefix(line, commandPrefix) {
+			continue
+		}
+
+		rest := strings.TrimPrefix(line, commandPrefix)
+		if rest != "" && rest[0] != ' ' && rest[0] != '\t' {
+			// e.g. "/cyclones" is not a command
+			continue
+		}
+
+		fields := strings.Fields(rest)
+		if len(fields) == 0 {
+			return &command{verb: "help"}, true
+		}
+
+		args := strings.TrimSpace(strings.TrimPrefix(strings.TrimSpace(rest), fields[0]))
+		if trailing := strings.TrimSpace(strings.Join(lines[i+1:], "\n")); trailing != "" {
+			args = strings.TrimSpace(args + "\n" + trailing)
+		}
+
+		return &command{verb: strings.ToLower(fields[0]), args: args}, true
+	}
+
+	return nil, false
+}
+
+// isPaused reports whether automatic reviews are paused on a PR
+func isPaused(pr *github.PullRequest) bool {
+	for _, label := range pr.Labels {
+		if label.GetName() == pausedLabel {
+			return true
+		}
+	}
+	return false
+}
+
+// RunCommand executes a slash command issued in a PR comment. Errors are returned if the
+// command may succeed when retried.
+func (bot *CycloneBot) RunCommand(ctx context.Context, cmd *command, cc commandContext) error {
+	owner := cc.repo.GetOwner().GetLogin()
+	repoName := cc.repo.GetName()
+
+	log.Printf("Running command '%s' from %s on PR #%d in %s/%s", cmd.verb, cc.author, cc.prNumber, owner, repoName)
+
+	githubClient, err := bot.createInstallationClient(ctx, cc.repo, cc.installationID)
+	if err != nil {
+		return err
+	}
+
+	canWrite, err := githubClient.HasWriteAccess(ctx, owner, repoName, cc.author)
+	if err != nil {
+		return fmt.Errorf("failed to check permissions of %s: %w", cc.author, err)
+	}
+	if !canWrite {
+		log.Printf("Ignoring command from %s - no write access to %s/%s", cc.author, owner, repoName)
+		bot.react(ctx, githubClient, cc, "-1")
+		return nil
+	}
+
+	switch cmd.verb {
+	case "review":
+		bot.react(ctx, githubClient, cc, "eyes")
+		pr, err := githubClient.GetPullRequest(ctx, owner, repoName, cc.prNumber)
+		if err != nil {
+			return fmt.Errorf("failed to fetch PR #%d: %w", cc.prNumber, err)
+		}
+		if cmd.args != "" {
+			return bot.ProcessFileReview(ctx, cc.repo, pr, cc.installationID, strings.Fields(cmd.args)[0])
+		}
+		return bot.ProcessPullRequest(ctx, cc.repo, pr, cc.installationID)
+
+	case "explain":
+		if cmd.args == "" {
+			bot.react(ctx, githubClient, cc, "confused")
+			bot.reply(ctx, githubClient, cc, "Please add a question, e.g. `/cyclone explain why is the cache invalidated here?` 🌪️")
+			return nil
+		}
+		bot.react(ctx, githubClient, cc, "eyes")
+		return bot.explain(ctx, githubClient, cc, cmd.args)
+
+	case "pause", "ignore":
+		bot.react(ctx, githubClient, cc, "+1")
+		if err := githubClient.AddLabel(ctx, owner, repoName, cc.prNumber, pausedLabel); err != nil {
+			return fmt.Errorf("failed to pause reviews: %w", err)
+		}
+		bot.reply(ctx, githubClient, cc, "⏸️ Automatic Cyclone reviews are paused for this PR. Use `/cyclone resume` to turn them back on.")
+
+	case "resume":
+		bot.react(ctx, githubClient, cc, "+1")
+		if err := githubClient.RemoveLabel(ctx, owner, repoName, cc.prNumber, pausedLabel); err != nil {
+			return fmt.Errorf("failed to resume reviews: %w", err)
+		}
+		bot.reply(ctx, githubClient, cc, "▶️ Automatic Cyclone reviews are resumed for this PR.")
+
+	case "help":
+		bot.react(ctx, githubClient, cc, "+1")
+		bot.reply(ctx, githubClient, cc, commandHelp)
+
+	default:
+		bot.react(ctx, githubClient, cc, "confused")
+		bot.reply(ctx, githubClient, cc, fmt.Sprintf("Unknown command `%s`.\n\n%s", cmd.verb, commandHelp))
+	}
+	return nil
+}
+
+// explain answers a question about the PR's changes
+func (bot *CycloneBot) explain(ctx context.Context, githubClient *review.GitHubClient, cc commandContext, question string) error {
+	owner := cc.repo.GetOwner().GetLogin()
+	repoName := cc.repo.GetName()
+
+	pr, err := githubClient.GetPullRequest(ctx, owner, repoName, cc.prNumber)
+	if err != nil {
+		return fmt.Errorf("failed to fetch PR #%d: %w", cc.prNumber, err)
+	}
+
+	// Answer with the repository's configuration, including its own .cyclone.yml
+	repoConfig, err := bot.repositoryConfig(ctx, githubClient, cc.repo, pr, cc.installationID)
+	if err != nil {
+		return err
+	}
+	if repoConfig == nil {
+		return nil
+	}
+
+	diff, err := githubClient.GetPRDiff(ctx, owner, repoName, cc.prNumber)
+	if err != nil {
+		return fmt.Errorf("failed to get diff of PR #%d: %w", cc.prNumber, err)
+	}
+
+	answer, err := bot.aiClient.ExplainDiff(ctx, diff.Text, pr.GetTitle(), pr.GetBody(), question, repoConfig)
+	if err != nil && ctx.Err() != nil {
+		// Timed out or shutting down, the job is run again
+		return fmt.Errorf("explaining PR #%d interrupted: %w", cc.prNumber, err)
+	}
+	if err != nil {
+		log.Printf("Error explaining PR #%d: %v", cc.prNumber, err)
+		answer = "⚠️ Cyclone couldn't reach its AI provider to answer this question. Please try again later."
+	}
+	bot.reply(ctx, githubClient, cc, answer)
+	return nil
+}
+
+// reply answers a command where it was issued: in the review thread or the PR conversation
+func (bot *CycloneBot) reply(ctx context.Context, githubClient *review.GitHubClient, cc commandContext, body string) {
+	owner := cc.repo.GetOwner().GetLogin()
+	repoName := cc.repo.GetName()
+
+	var err error
+	if cc.isReviewComment {
+		err = githubClient.ReplyToReviewComment(ctx, owner, repoName, cc.prNumber, cc.threadID, body)
+	} else {
+		err = githubClient.PostComment(ctx, owner, repoName, cc.prNumber, body)
+	}
+	if err != nil {
+		log.Printf("Error replying to command: %v", err)
+	}
+}
+
+// react acknowledges a command comment with a reaction
+func (bot *CycloneBot) react(ctx context.Context, githubClient *review.GitHubClient, cc commandContext, content string) {
+	owner := cc.repo.GetOwner().GetLogin()
+	repoName := cc.repo.GetName()
+
+	var err error
+	if cc.isReviewComment {
+		err = githubClient.ReactToReviewComment(ctx, owner, repoName, cc.commentID, content)
+	} else {
+		err = githubClient.ReactToIssueComment(ctx, owner, repoName, cc.commentID, content)
+	}
+	if err != nil {
+		log.Printf("Error adding reaction: %v", err)
+	}
+}
@@ -0,0 +1,74 @@
+package bot
+
+import (
+	"context"
+	"fmt"
+	"log"
+
+	"github.com/google/go-github/v57/github"
+
+	"cyclone/internal/review"
+)
+
+// ProcessThreadReply answers a developer's reply in a review thread started by Cyclone
+func (bot *CycloneBot) ProcessThreadReply(ctx context.Context, repo *github.Repository, pr *github.PullRequest, reply *github.PullRequestComment, installationID int64) error {
+	owner := repo.GetOwner().GetLogin()
+	repoName := repo.GetName()
+	prNumber := pr.GetNumber()
+	rootID := reply.GetInReplyTo()
+
+	githubClient, err := bot.createInstallationClient(ctx, repo, installationID)
+	if err != nil {
+		return err
+	}
+
+	login := bot.botLogin(repo)
+
+	// Don't answer our own replies
+	if reply.GetUser().GetLogin() == login {
+		return nil
+	}
+
+	thread, err := githubClient.GetReviewThread(ctx, owner, repoName, prNumber, rootID)
+	if err != nil {
+		return fmt.Errorf("failed to fetch review thread %d: %w", rootID, err)
+	}
+
+	if len(thread) == 0 || thread[0].GetID() != rootID || thread[0].GetUser().GetLogin() != login {
+		// Not a thread Cyclone started
+		return nil
+	}
+
+	// Answer with the repository's configuration, including its own .cyclone.yml
+	repoConfig, err := bot.repositoryConfig(ctx, githubClient, repo, pr, installationID)
+	if err != nil {
+		return err
+	}
+	if repoConfig == nil {
+		return nil
+	}
+
+	log.Printf("Answering reply from %s in review thread %d on PR #%d in %s/%s", reply.GetUser().GetLogin(), rootID, prNumber, owner, repoName)
+
+	var conversation []review.ThreadComment
+	for _, c := range thread {
+		conversation = append(conversation, review.ThreadComment{
+			Author:      c.GetUser().GetLogin(),
+			Body:        c.GetBody(),
+			FromCyclone: c.GetUser().GetLogin() == login,
+		})
+	}
+
+	root := thread[0]
+	answer, err := bot.aiClient.ReplyInThread(ctx, pr.GetTitle(), root.GetPath(), root.GetDiffHunk(), conversation, repoConfig)
+	if err != nil {
+		return fmt.Errorf("failed to generate reply in review thread %d: %w", rootID, err)
+	}
+
+	if err := githubClient.ReplyToReviewComment(ctx, owner, repoName, prNumber, rootID, answer); err != nil {
+		return fmt.Errorf("failed to reply in review thread %d: %w", rootID, err)
+	}
+
+	log.Printf("Successfully replied in review thread %d on PR #%d", rootID, prNumber)
+	return nil
+}
@@ -2,150 +2,444 @@ package bot
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"log"
 	"net/http"
+	"strings"
 
 	"github.com/google/go-github/v57/github"
 
 	"cyclone/internal/config"
+	"cyclone/internal/jobs"
 	"cyclone/internal/review"
 )
 
 // CycloneBot handles GitHub operations and AI integration
 type CycloneBot struct {
-	githubClient   *review.GitHubClient
-	githubApp      *review.GitHubAppAuth // Add this
+	hosts          []*githubHost // GitHub instances served, the default one first
 	aiClient       *review.AIClient
 	config         *config.Config
 	configProvider config.ConfigProvider
+	provisioner    *config.Provisioner // nil unless repositories are configured in Supabase
+	jobs           *jobs.Pool          // runs the work webhooks are turned into
+	state          *reviewState
 }
 
 // New creates a new Cyclone bot instance
 func New(cfg *config.Config, configProvider config.ConfigProvider) (*CycloneBot, error) {
-	// Initialize GitHub client (keep for fallback)
-	githubClient, err := review.NewGitHubClient(cfg.GitHubToken)
-	if err != nil {
-		return nil, fmt.Errorf("failed to create GitHub client: %w", err)
-	}
-
-	// Initialize GitHub App auth
-	var githubApp *review.GitHubAppAuth
-	if cfg.GitHubAppID != 0 && cfg.GitHubPrivateKeyPath != "" {
-		githubApp, err = review.NewGitHubAppAuth(cfg.GitHubAppID, cfg.GitHubPrivateKeyPath)
+	// Connect to github.com and/or GitHub Enterprise Server
+	var hosts []*githubHost
+	for _, hostConfig := range cfg.GitHubHosts() {
+		host, err := newGitHubHost(hostConfig)
 		if err != nil {
-			log.Printf("Warning: Failed to initialize GitHub App auth: %v", err)
-			// Continue with personal token
+			return nil, err
 		}
+		hosts = append(hosts, host)
 	}
 
-	// Initialize AI client
-	aiClient := review.NewAIClient(cfg.AnthropicToken, "claude-sonnet-4-20250514")
-
-	return &CycloneBot{
-		githubClient:   githubClient,
-		githubApp:      githubApp,
-		aiClient:       aiClient,
-		config:         cfg,
-		configProvider: configProvider,
-	}, nil
-}
-
-func (bot *CycloneBot) createInstallationClient(ctx context.Context, installationID int64) (*review.GitHubClient, error) {
-	if bot.githubApp == nil {
-		// Fallback to personal token
-		return bot.githubClient, nil
+	// Initialize AI providers and client
+	var providers []review.Provider
+	if cfg.AnthropicToken != "" {
+		providers = append(providers, review.NewAnthropicProvider(cfg.AnthropicToken, "claude-sonnet-4-20250514"))
+	}
+	if cfg.OpenAIAPIKey != "" || cfg.OpenAIBaseURL != "" {
+		providers = append(providers, review.NewOpenAIProvider(cfg.OpenAIBaseURL, cfg.OpenAIAPIKey, "gpt-4o"))
+	}
+	if cfg.OllamaURL != "" {
+		providers = append(providers, review.NewOllamaProvider(cfg.OllamaURL, "qwen2.5-coder"))
 	}
 
-	// Get installation token
-	token, err := bot.githubApp.GetInstallationToken(ctx, installationID)
+	aiClient, err := review.NewAIClient(cfg.AIProvider, cfg.AIModel, cfg.AIFallbackModel, providers...)
 	if err != nil {
-		return nil, fmt.Errorf("failed to get installation token: %w", err)
+		return nil, fmt.Errorf("failed to create AI client: %w", err)
+	}
+
+	// Installations and their repositories are provisioned into Supabase from webhooks
+	var provisioner *config.Provisioner
+	if cfg.ConfigBackend == config.ConfigBackendSupabase {
+		provisioner = config.NewProvisioner(cfg)
 	}
 
-	// Create client with installation token
-	return review.NewGitHubClient(token)
+	bot := &CycloneBot{
+		hosts:          hosts,
+		aiClient:       aiClient,
+		config:         cfg,
+		configProvider: configProvider,
+		provisioner:    provisioner,
+		jobs:           jobs.NewPool(newJobQueue(cfg), cfg.JobWorkers, cfg.JobInstallationConcurrency, cfg.JobMaxAttempts),
+		state:          newReviewState(),
+	}
+	bot.handleJobs()
+	return bot, nil
 }
 
 // SetupRoutes configures HTTP routes for the bot
 func (bot *CycloneBot) SetupRoutes() {
 	http.HandleFunc("/webhook", bot.handleWebhook)
 	http.HandleFunc("/health", bot.healthCheck)
+	if bot.config.AdminToken != "" {
+		http.HandleFunc("/admin/config/invalidate", bot.handleInvalidateConfig)
+		http.HandleFunc("/admin/jobs", bot.handleListJobs)
+		http.HandleFunc("/admin/jobs/requeue", bot.handleRequeueJob)
+	}
 	http.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
 		fmt.Fprintf(w, "Cyclone AI Code Review Bot\nEndpoints:\n- POST /webhook (GitHub webhooks)\n- GET /health (health check)")
 	})
 }
 
-// ProcessPullRequest handles the main logic for reviewing a PR
-func (bot *CycloneBot) ProcessPullRequest(repo *github.Repository, pr *github.PullRequest, installationID int64) {
-	ctx := context.Background()
+// ProcessPullRequest handles the main logic for reviewing a PR. Errors are returned if
+// the review may succeed when retried.
+func (bot *CycloneBot) ProcessPullRequest(ctx context.Context, repo *github.Repository, pr *github.PullRequest, installationID int64) error {
+	githubClient, err := bot.createInstallationClient(ctx, repo, installationID)
+	if err != nil {
+		return err
+	}
+
+	// Get repository-specific configuration, including the repository's own .cyclone.yml
+	repoConfig, err := bot.repositoryConfig(ctx, githubClient, repo, pr, installationID)
+	if err != nil {
+		return err
+	}
+	if repoConfig == nil {
+		return nil
+	}
+
+	return bot.reviewPullRequest(ctx, githubClient, repo, pr, repoConfig)
+}
 
+// reviewPullRequest reviews the whole PR with the repository's resolved configuration
+func (bot *CycloneBot) reviewPullRequest(ctx context.Context, githubClient *review.GitHubClient, repo *github.Repository, pr *github.PullRequest, repoConfig *config.RepositoryConfig) error {
 	owner := repo.GetOwner().GetLogin()
 	repoName := repo.GetName()
 	prNumber := pr.GetNumber()
 
 	log.Printf("Processing PR #%d in %s/%s", prNumber, owner, repoName)
 
-	// Get repository-specific configuration
-	repoConfig, er := bot.configProvider.GetRepositoryConfig(ctx, owner, repoName, installationID)
-	if repoConfig == nil {
-		log.Printf("Repository %s/%s not found in configuration - skipping review: %s", owner, repoName, er)
-		return
-	}
+	check := startReviewCheck(ctx, githubClient, repo, pr)
 
 	// Check PR size before proceeding
-	sizeCheck := bot.checkPRSize(pr)
+	sizeCheck := bot.checkPRSize(pr, repoConfig)
 	if !sizeCheck.ShouldReview {
 		log.Printf("PR #%d is too large - posting skip message instead of review", prNumber)
 
 		// Post skip message as a regular comment
-		if err := bot.githubClient.PostComment(ctx, owner, repoName, prNumber, sizeCheck.SkipMessage); err != nil {
+		if err := githubClient.PostComment(ctx, owner, repoName, prNumber, sizeCheck.SkipMessage); err != nil {
 			log.Printf("Error posting skip message: %v", err)
 		}
-		return
+		check.skip(ctx, "PR too large to review", sizeCheck.SkipMessage)
+		return nil
 	}
 
 	log.Printf("Using precision: %s for repository: %s", repoConfig.Precision, repoName)
 
-	githubClient, err := bot.createInstallationClient(ctx, installationID)
+	// Get the PR diff
+	diff, err := githubClient.GetPRDiff(ctx, owner, repoName, prNumber)
 	if err != nil {
-		log.Printf("Error creating installation client: %v", err)
-		return
+		check.skip(ctx, "Review failed", "🌪️ Cyclone couldn't fetch the changes of this PR.")
+		return fmt.Errorf("failed to get diff of PR #%d: %w", prNumber, err)
 	}
 
-	// Get the PR diff
-	diff, err := githubClient.GetPRDiff(ctx, owner, repoName, prNumber)
+	if err := bot.reviewAndPost(ctx, githubClient, repo, pr, diff, repoConfig, sizeCheck.WarningMessage, check); err != nil {
+		return fmt.Errorf("failed to post review of PR #%d: %w", prNumber, err)
+	}
+	bot.state.MarkReviewed(newPRKey(repo, pr), pr.GetHead().GetSHA())
+
+	log.Printf("Successfully posted AI review for PR #%d", prNumber)
+	return nil
+}
+
+// ProcessFileReview reviews a single file of a PR, as requested via `/cyclone review <path>`
+func (bot *CycloneBot) ProcessFileReview(ctx context.Context, repo *github.Repository, pr *github.PullRequest, installationID int64, path string) error {
+	owner := repo.GetOwner().GetLogin()
+	repoName := repo.GetName()
+	prNumber := pr.GetNumber()
+
+	log.Printf("Processing review of %s in PR #%d in %s/%s", path, prNumber, owner, repoName)
+
+	githubClient, err := bot.createInstallationClient(ctx, repo, installationID)
+	if err != nil {
+		return err
+	}
+
+	// Get repository-specific configuration, including the repository's own .cyclone.yml
+	repoConfig, err := bot.repositoryConfig(ctx, githubClient, repo, pr, installationID)
+	if err != nil {
+		return err
+	}
+	if repoConfig == nil {
+		return nil
+	}
+
+	diff, err := githubClient.GetPRFileDiff(ctx, owner, repoName, prNumber, path)
+	if err != nil && !errors.Is(err, review.ErrFileNotFound) {
+		return fmt.Errorf("failed to get diff of %s in PR #%d: %w", path, prNumber, err)
+	}
+	if err != nil || strings.TrimSpace(diff.Text) == "" {
+		log.Printf("Cannot review %s in PR #%d: %v", path, prNumber, err)
+		if err := githubClient.PostComment(ctx, owner, repoName, prNumber, fmt.Sprintf("🌪️ Cyclone couldn't find reviewable changes to `%s` in this PR.", path)); err != nil {
+			log.Printf("Error posting comment: %v", err)
+		}
+		return nil
+	}
+
+	preamble := fmt.Sprintf("📄 **Single-file review** of `%s`\n\n---\n\n", path)
+	check := startReviewCheck(ctx, githubClient, repo, pr)
+	if err := bot.reviewAndPost(ctx, githubClient, repo, pr, diff, repoConfig, preamble, check); err != nil {
+		return fmt.Errorf("failed to post review of %s in PR #%d: %w", path, prNumber, err)
+	}
+
+	log.Printf("Successfully posted AI review of %s for PR #%d", path, prNumber)
+	return nil
+}
+
+// ProcessIncrementalReview reviews only the commits pushed since Cyclone's last review of the PR
+func (bot *CycloneBot) ProcessIncrementalReview(ctx context.Context, repo *github.Repository, pr *github.PullRequest, installationID int64) error {
+	owner := repo.GetOwner().GetLogin()
+	repoName := repo.GetName()
+	prNumber := pr.GetNumber()
+	headSHA := pr.GetHead().GetSHA()
+
+	log.Printf("Processing incremental review for PR #%d in %s/%s", prNumber, owner, repoName)
+
+	githubClient, err := bot.createInstallationClient(ctx, repo, installationID)
+	if err != nil {
+		return err
+	}
+
+	// Get repository-specific configuration, including the repository's own .cyclone.yml
+	repoConfig, err := bot.repositoryConfig(ctx, githubClient, repo, pr, installationID)
 	if err != nil {
-		log.Printf("Error getting PR diff: %v", err)
-		return
+		return err
+	}
+	if repoConfig == nil {
+		return nil
+	}
+	if !repoConfig.TriggersOn("synchronize") {
+		log.Printf("Action synchronize doesn't trigger reviews in %s - ignoring PR #%d", repo.GetFullName(), prNumber)
+		return nil
 	}
 
-	// Get AI review with repository-specific configuration
-	reviewResult := bot.aiClient.GenerateReview(diff, pr.GetTitle(), pr.GetBody(), repoConfig)
+	// Prefer the in-memory state, fall back to Cyclone's reviews on GitHub (e.g. after a restart)
+	key := newPRKey(repo, pr)
+	baseSHA := bot.state.LastReviewedSHA(key)
+	if baseSHA == "" {
+		baseSHA, err = githubClient.LastReviewedCommit(ctx, owner, repoName, prNumber, bot.botLogin(repo))
+		if err != nil {
+			return fmt.Errorf("failed to look up last reviewed commit of PR #%d: %w", prNumber, err)
+		}
+	}
 
-	// Prepend size warning if applicable
-	if sizeCheck.WarningMessage != "" {
-		reviewResult.Summary = sizeCheck.WarningMessage + reviewResult.Summary
+	if baseSHA == "" {
+		log.Printf("PR #%d has no previous Cyclone review - running a full review", prNumber)
+		return bot.reviewPullRequest(ctx, githubClient, repo, pr, repoConfig)
+	}
+
+	if baseSHA == headSHA {
+		log.Printf("PR #%d already reviewed at %s - skipping", prNumber, shortSHA(headSHA))
+		return nil
+	}
+
+	comparison, err := githubClient.GetCompareDiff(ctx, owner, repoName, baseSHA, headSHA)
+	if err != nil || comparison.Status == "diverged" {
+		// The previously reviewed commit was rewritten by a force-push, so there is
+		// no meaningful delta to review - review the whole PR again instead
+		log.Printf("Cannot compare %s...%s for PR #%d (err: %v) - running a full review", shortSHA(baseSHA), shortSHA(headSHA), prNumber, err)
+		return bot.reviewPullRequest(ctx, githubClient, repo, pr, repoConfig)
+	}
+
+	// Every pushed head commit gets its own check run, so a required check doesn't block the PR
+	check := startReviewCheck(ctx, githubClient, repo, pr)
+
+	if strings.TrimSpace(comparison.Diff.Text) == "" {
+		log.Printf("No reviewable changes in %s...%s for PR #%d - skipping", shortSHA(baseSHA), shortSHA(headSHA), prNumber)
+		check.complete(ctx, review.ConclusionSuccess, "No reviewable changes", fmt.Sprintf("🌪️ The commits pushed since `%s` contain no reviewable changes.", shortSHA(baseSHA)), nil)
+		bot.state.MarkReviewed(key, headSHA)
+		return nil
+	}
+
+	sizeCheck := bot.checkSize(comparison.Files, comparison.Additions, comparison.Deletions, repoConfig)
+	if !sizeCheck.ShouldReview {
+		log.Printf("Push to PR #%d is too large for an incremental review - skipping", prNumber)
+		check.skip(ctx, "Push too large to review", sizeCheck.SkipMessage)
+		return nil
+	}
+
+	preamble := fmt.Sprintf("🔁 **Incremental review** of %d new commit(s) (`%s...%s`)\n\n---\n\n",
+		comparison.TotalCommits, shortSHA(baseSHA), shortSHA(headSHA))
+
+	if err := bot.reviewAndPost(ctx, githubClient, repo, pr, comparison.Diff, repoConfig, sizeCheck.WarningMessage+preamble, check); err != nil {
+		return fmt.Errorf("failed to post incremental review of PR #%d: %w", prNumber, err)
+	}
+	bot.state.MarkReviewed(key, headSHA)
+
+	log.Printf("Successfully posted incremental AI review for PR #%d", prNumber)
+	return nil
+}
+
+// reviewAndPost generates an AI review for the diff, posts it on the PR's head commit and
+// completes the check run with the outcome
+func (bot *CycloneBot) reviewAndPost(ctx context.Context, githubClient *review.GitHubClient, repo *github.Repository, pr *github.PullRequest, diff *review.PRDiff, repoConfig *config.RepositoryConfig, preamble string, check *reviewCheck) error {
+	owner, repoName := repo.GetOwner().GetLogin(), repo.GetName()
+
+	// Leave out the files the repository's path globs exclude
+	diff.FilterPaths(repoConfig.ShouldReviewPath)
+	if len(diff.Files) == 0 {
+		log.Printf("All changed files of PR #%d are excluded from review - skipping", pr.GetNumber())
+		check.complete(ctx, review.ConclusionSuccess, "No reviewable changes", "🌪️ All changed files are excluded from review by the repository's configuration.", nil)
+		return nil
+	}
+
+	// Send the code around the changes along, so the AI knows what the hunks refer to
+	headFiles := review.NewHeadFiles(ctx, githubClient, owner, repoName, pr.GetHead().GetSHA())
+	review.AddContext(diff, headFiles)
+
+	// Get AI review with repository-specific configuration, diffs too large for a
+	// single request are reviewed in chunks
+	var reviewResult review.ReviewResult
+	var err error
+	if review.EstimateTokens(diff.Text) > config.CHUNK_TOKEN_BUDGET {
+		reviewResult, err = bot.aiClient.GenerateChunkedReview(ctx, diff, pr.GetTitle(), pr.GetBody(), repoConfig)
+	} else {
+		reviewResult, err = bot.aiClient.GenerateReview(ctx, diff, pr.GetTitle(), pr.GetBody(), repoConfig)
+	}
+	if err != nil && ctx.Err() != nil {
+		// Timed out or shutting down, the job is run again. ctx is done, so the check run
+		// is completed without it rather than left in progress.
+		check.skip(context.WithoutCancel(ctx), "Review interrupted", "🌪️ Cyclone's review of this PR was interrupted and will be retried.")
+		return fmt.Errorf("review of PR #%d interrupted: %w", pr.GetNumber(), err)
+	}
+	if err != nil {
+		// Tell the author instead of silently leaving the PR without a review
+		if postErr := githubClient.PostComment(ctx, owner, repoName, pr.GetNumber(), reviewUnavailableMessage); postErr != nil {
+			log.Printf("Error posting review unavailable notice: %v", postErr)
+		}
+		check.skip(ctx, "AI review unavailable", reviewUnavailableMessage)
+
+		// The AI client retried already and the author was told, so the job isn't retried
+		return jobs.Permanent(fmt.Errorf("failed to generate review: %w", err))
+	}
+
+	// Don't repeat findings Cyclone already raised on the PR. Repeats of open threads still
+	// count towards the review event and check conclusion, resolved ones don't.
+	threads, login := bot.earlierThreads(ctx, githubClient, repo, pr.GetNumber())
+	reviewResult, repeated := review.DedupeAgainstThreads(reviewResult, diff, threads, login)
+
+	// Drop comments below the repository's threshold, both from what is posted and from what
+	// counts, so the review event and check conclusion match the posted comments
+	minSeverity := review.ParseSeverity(repoConfig.MinSeverity)
+	reviewResult = review.FilterBySeverity(reviewResult, minSeverity)
+	repeated = review.FilterBySeverity(review.ReviewResult{Comments: repeated}, minSeverity).Comments
+	counted := reviewResult
+	counted.Comments = append(append([]review.ReviewComment{}, repeated...), reviewResult.Comments...)
+
+	// Make sure every comment lands on a line of the diff, otherwise GitHub rejects the whole review
+	anchored := review.AnchorComments(reviewResult, diff)
+
+	// Only suggestions matching the file at the head commit become one-click fixes
+	anchored = review.RenderSuggestions(anchored, headFiles.Content)
+	anchored = review.AddFilesNotReviewed(anchored, diff)
+
+	// Prepend size warning or incremental notice if applicable
+	if preamble != "" {
+		anchored.Summary = insertAfterHeader(anchored.Summary, preamble)
+	}
+
+	// Request changes or approve according to the repository's review policy. Comments that
+	// couldn't be anchored still count, and a PR with files the AI failed to review is never approved.
+	event := review.ReviewEvent(counted, repoConfig.ReviewPolicy, repoConfig.ApproveClean)
+	if event == review.ReviewEventApprove && hasUnreviewedChunks(diff) {
+		event = review.ReviewEventComment
 	}
 
 	// Post the review with line-specific comments
-	if err := githubClient.PostReview(ctx, owner, repoName, prNumber, reviewResult); err != nil {
-		log.Printf("Error posting PR review: %v", err)
-		return
+	if err := githubClient.PostReview(ctx, owner, repoName, pr.GetNumber(), pr.GetHead().GetSHA(), event, anchored); err != nil {
+		check.skip(ctx, "Review failed", "🌪️ Cyclone couldn't post its review on this PR.")
+		return err
 	}
 
-	log.Printf("Successfully posted AI review for PR #%d", prNumber)
+	// A later review without blocking items lifts Cyclone's earlier change requests
+	if event != review.ReviewEventRequestChanges {
+		message := fmt.Sprintf("🌪️ Cyclone's review of %s found no blocking items.", shortSHA(pr.GetHead().GetSHA()))
+		if err := githubClient.DismissChangeRequests(ctx, owner, repoName, pr.GetNumber(), message); err != nil {
+			log.Printf("Error dismissing earlier change requests: %v", err)
+		}
+	}
+
+	// Hide Cyclone's earlier comments on code that has changed since
+	for _, thread := range review.OutdatedThreads(threads, login) {
+		if err := githubClient.MinimizeOutdatedComment(ctx, thread.RootID); err != nil {
+			log.Printf("Error hiding outdated comment on %s: %v", thread.Path, err)
+		}
+	}
+
+	check.completeWithReview(ctx, counted, anchored, repoConfig.CheckPolicy)
+	return nil
+}
+
+// earlierThreads returns the review threads of a PR and Cyclone's login, to recognize the
+// threads Cyclone started. Findings may be repeated if they can't be listed.
+func (bot *CycloneBot) earlierThreads(ctx context.Context, githubClient *review.GitHubClient, repo *github.Repository, prNumber int) ([]review.ReviewThread, string) {
+	login := bot.botLogin(repo)
+
+	threads, err := githubClient.ListReviewThreads(ctx, repo.GetOwner().GetLogin(), repo.GetName(), prNumber)
+	if err != nil {
+		log.Printf("Error listing review threads, not checking earlier comments: %v", err)
+		return nil, login
+	}
+
+	return threads, login
+}
+
+// hasUnreviewedChunks reports whether parts of a chunked review failed
+func hasUnreviewedChunks(diff *review.PRDiff) bool {
+	for _, skipped := range diff.Skipped {
+		if skipped.Reason == review.SkipReasonAIFailed {
+			return true
+		}
+	}
+	return false
+}
+
+// reviewUnavailableMessage is posted when no AI model could review the PR
+const reviewUnavailableMessage = `## 🌪️ Cyclone Notice
+
+⚠️ **AI review unavailable**
+
+Cyclone couldn't reach its AI provider, even after retrying and falling back to another model, so this PR has not been reviewed.
+
+Comment ` + "`/cyclone review`" + ` to try again later.`
+
+// insertAfterHeader places text right below the Cyclone review heading, keeping the
+// heading first so the review can still be recognized as Cyclone's
+func insertAfterHeader(summary, text string) string {
+	if !strings.HasPrefix(summary, review.ReviewHeader) {
+		return text + summary
+	}
+	rest := strings.TrimLeft(strings.TrimPrefix(summary, review.ReviewHeader), "\n")
+	return review.ReviewHeader + "\n\n" + text + rest
+}
+
+// shortSHA abbreviates a commit SHA for display
+func shortSHA(sha string) string {
+	if len(sha) > 7 {
+		return sha[:7]
+	}
+	return sha
 }
 
 // checkPRSize evaluates if a PR is too large for review
-func (bot *CycloneBot) checkPRSize(pr *github.PullRequest) review.PRSizeCheck {
-	files := pr.GetChangedFiles()
-	additions := pr.GetAdditions()
-	deletions := pr.GetDeletions()
+func (bot *CycloneBot) checkPRSize(pr *github.PullRequest, repoConfig *config.RepositoryConfig) review.PRSizeCheck {
+	return bot.checkSize(pr.GetChangedFiles(), pr.GetAdditions(), pr.GetDeletions(), repoConfig)
+}
+
+// checkSize evaluates if a set of changes is too large for review under the repository's limits
+func (bot *CycloneBot) checkSize(files, additions, deletions int, repoConfig *config.RepositoryConfig) review.PRSizeCheck {
 	totalChanges := additions + deletions
+	maxFiles, maxAdditions, maxTotalChanges := repoConfig.Limits()
 
 	// Hard limits - skip review entirely
-	if files > config.MAX_FILES_FOR_REVIEW {
+	if files > maxFiles {
 		return review.PRSizeCheck{
 			ShouldReview: false,
 			SkipMessage: fmt.Sprintf(`## 🌪️ Cyclone Notice
@@ -165,11 +459,11 @@ This PR modifies **%d files**, which exceeds our limit of %d files for automated
 - Each PR should ideally change < 15 files and < 400 lines
 - Group related changes together (e.g., "Add user authentication", "Update API endpoints")
 
-*Happy to review once split into smaller chunks!* 🌪️`, files, config.MAX_FILES_FOR_REVIEW),
+*Happy to review once split into smaller chunks!* 🌪️`, files, maxFiles),
 		}
 	}
 
-	if additions > config.MAX_ADDITIONS_FOR_REVIEW {
+	if additions > maxAdditions {
 		return review.PRSizeCheck{
 			ShouldReview: false,
 			SkipMessage: fmt.Sprintf(`## 🌪️ Cyclone Notice
@@ -189,11 +483,11 @@ This PR adds **%d lines**, which exceeds our limit of %d lines for automated rev
 - Split features into logical, reviewable chunks
 - Consider feature flags for large features
 
-*Ready to provide detailed feedback on smaller PRs!* 🌪️`, additions, config.MAX_ADDITIONS_FOR_REVIEW),
+*Ready to provide detailed feedback on smaller PRs!* 🌪️`, additions, maxAdditions),
 		}
 	}
 
-	if totalChanges > config.MAX_TOTAL_CHANGES {
+	if totalChanges > maxTotalChanges {
 		return review.PRSizeCheck{
 			ShouldReview: false,
 			SkipMessage: fmt.Sprintf(`## 🌪️ Cyclone Notice
@@ -204,7 +498,7 @@ This PR has **%d total changes** (+%d, -%d), exceeding our limit of %d changes.
 
 **Recommendation**: Break this into smaller, focused PRs for better review quality and faster merge times.
 
-*Each PR should tell a focused story about one specific change.* 🌪️`, totalChanges, additions, deletions, config.MAX_TOTAL_CHANGES),
+*Each PR should tell a focused story about one specific change.* 🌪️`, totalChanges, additions, deletions, maxTotalChanges),
 		}
 	}
 
@@ -0,0 +1,169 @@
+package bot
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net/url"
+	"sync"
+	"time"
+
+	"github.com/google/go-github/v57/github"
+
+	"cyclone/internal/config"
+	"cyclone/internal/jobs"
+	"cyclone/internal/review"
+)
+
+// githubHost is a GitHub instance Cyclone serves, github.com or a GitHub Enterprise Server,
+// with the clients to reach it
+type githubHost struct {
+	name          string
+	enterprise    bool                  // GitHub Enterprise Server rather than github.com
+	githubClient  *review.GitHubClient  // personal access token, nil with a GitHub App only
+	githubApp     *review.GitHubAppAuth // nil without a GitHub App
+	webhookSecret string
+	login         string // login Cyclone posts as
+
+	clientsMu sync.Mutex
+	clients   map[int64]*review.GitHubClient // long-lived clients per installation
+}
+
+// newGitHubHost connects to a GitHub instance. It fails if the configured credentials
+// don't work, rather than on the first PR.
+func newGitHubHost(cfg config.GitHubHost) (*githubHost, error) {
+	host := &githubHost{
+		name:          cfg.Name(),
+		enterprise:    cfg.APIURL != "",
+		webhookSecret: cfg.WebhookSecret,
+		clients:       make(map[int64]*review.GitHubClient),
+	}
+
+	// Initialize GitHub client with the personal access token, used for webhooks without an
+	// installation (optional with a GitHub App)
+	var err error
+	if cfg.Token != "" {
+		host.githubClient, err = review.NewGitHubClient(cfg.Token, cfg.APIURL, cfg.UploadURL)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create GitHub client for %s: %w", host.name, err)
+		}
+	}
+
+	// Initialize GitHub App auth
+	if cfg.AppID != 0 {
+		host.githubApp, err = review.NewGitHubAppAuth(cfg.AppID, cfg.PrivateKeyPath, cfg.PrivateKey, cfg.APIURL, cfg.UploadURL)
+		if err != nil {
+			return nil, fmt.Errorf("failed to initialize GitHub App auth for %s: %w", host.name, err)
+		}
+	}
+
+	if err := host.verify(); err != nil {
+		return nil, err
+	}
+
+	// Pick up rotated private keys without a restart
+	if host.githubApp != nil {
+		go host.githubApp.WatchPrivateKey(context.Background(), config.PRIVATE_KEY_POLL_INTERVAL)
+	}
+
+	return host, nil
+}
+
+// verify checks that every configured auth mode works and looks up the login Cyclone posts as
+func (h *githubHost) verify() error {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	if h.githubClient != nil {
+		login, err := h.githubClient.GetAuthenticatedLogin(ctx)
+		if err != nil {
+			return fmt.Errorf("personal access token for %s is not usable: %w", h.name, err)
+		}
+		h.login = login
+		log.Printf("Authenticated to %s as %s", h.name, login)
+	}
+
+	if h.githubApp != nil {
+		slug, err := h.githubApp.GetAppSlug(ctx)
+		if err != nil {
+			return fmt.Errorf("GitHub App for %s is not usable: %w", h.name, err)
+		}
+		// With an App, Cyclone posts as the App on installations
+		h.login = slug + "[bot]"
+		log.Printf("Authenticated to %s as App %s", h.name, slug)
+	}
+
+	return nil
+}
+
+// installationClient returns the client of an installation. Clients are kept per
+// installation and refresh their token when it is about to expire.
+func (h *githubHost) installationClient(installationID int64) (*review.GitHubClient, error) {
+	if h.githubApp == nil || installationID == 0 {
+		// Personal access token, e.g. for repository webhooks without an installation
+		if h.githubClient == nil {
+			return nil, jobs.Permanent(fmt.Errorf("event from %s without an installation and no personal access token configured", h.name))
+		}
+		return h.githubClient, nil
+	}
+
+	h.clientsMu.Lock()
+	client, ok := h.clients[installationID]
+	h.clientsMu.Unlock()
+	if ok {
+		return client, nil
+	}
+
+	// Fetch the first token now, so a broken installation fails here rather than on first use.
+	// This happens outside the lock so a slow installation doesn't hold up the others, the
+	// token source makes concurrent callers wait for a single request.
+	if _, err := h.githubApp.TokenSource(installationID).Token(); err != nil {
+		return nil, fmt.Errorf("failed to get installation token: %w", err)
+	}
+
+	client, err := h.githubApp.NewInstallationClient(installationID)
+	if err != nil {
+		return nil, err
+	}
+
+	h.clientsMu.Lock()
+	defer h.clientsMu.Unlock()
+	if existing, ok := h.clients[installationID]; ok {
+		// Another caller created the client meanwhile
+		return existing, nil
+	}
+	h.clients[installationID] = client
+	return client, nil
+}
+
+// hostNamed returns the GitHub instance with a host name
+func (bot *CycloneBot) hostNamed(name string) (*githubHost, bool) {
+	for _, host := range bot.hosts {
+		if host.name == name {
+			return host, true
+		}
+	}
+	return nil, false
+}
+
+// hostOf returns the GitHub instance a repository lives on, told by the host of its URL
+func (bot *CycloneBot) hostOf(repo *github.Repository) *githubHost {
+	if u, err := url.Parse(repo.GetHTMLURL()); err == nil {
+		if host, ok := bot.hostNamed(u.Hostname()); ok {
+			return host
+		}
+	}
+	return bot.hosts[0]
+}
+
+// createInstallationClient returns the client of an installation on the GitHub instance
+// of a repository
+func (bot *CycloneBot) createInstallationClient(ctx context.Context, repo *github.Repository, installationID int64) (*review.GitHubClient, error) {
+	return bot.hostOf(repo).installationClient(installationID)
+}
+
+// botLogin returns the login Cyclone posts as on the GitHub instance of a repository, e.g.
+// "cyclone-ai[bot]" for the GitHub App
+func (bot *CycloneBot) botLogin(repo *github.Repository) string {
+	return bot.hostOf(repo).login
+}
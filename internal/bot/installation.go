@@ -0,0 +1,63 @@
+package bot
+
+import (
+	"context"
+	"fmt"
+	"log"
+
+	"github.com/google/go-github/v57/github"
+
+	"cyclone/internal/config"
+)
+
+// ProcessInstallationEvent keeps the configured repositories in sync with the installations
+// of the GitHub App: new installations and repositories are added with the default
+// configuration, removed ones are soft-deleted
+func (bot *CycloneBot) ProcessInstallationEvent(ctx context.Context, event string, payload *WebhookPayload) error {
+	if bot.provisioner == nil || payload.Installation == nil {
+		log.Printf("Ignoring %s event: repositories are not provisioned with this configuration backend", event)
+		return nil
+	}
+
+	installationID := payload.Installation.ID
+	account := payload.Installation.Account.GetLogin()
+
+	log.Printf("Processing %s %s for installation %d on %s", event, payload.Action, installationID, account)
+
+	var err error
+	switch event + "." + payload.Action {
+	case "installation.created":
+		err = bot.provisioner.AddInstallation(ctx, installationID, account, repoNames(payload.Repositories))
+	case "installation.deleted":
+		err = bot.provisioner.RemoveInstallation(ctx, installationID)
+	case "installation.suspend":
+		err = bot.provisioner.SuspendInstallation(ctx, installationID, true)
+	case "installation.unsuspend":
+		err = bot.provisioner.SuspendInstallation(ctx, installationID, false)
+	case "installation_repositories.added":
+		err = bot.provisioner.AddRepositories(ctx, installationID, account, repoNames(payload.RepositoriesAdded))
+	case "installation_repositories.removed":
+		err = bot.provisioner.RemoveRepositories(ctx, installationID, account, repoNames(payload.RepositoriesRemoved))
+	default:
+		log.Printf("Ignoring %s action: %s", event, payload.Action)
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to provision installation %d: %w", installationID, err)
+	}
+
+	// Cached answers for the account's repositories, "not configured" included, are stale now
+	if cache, ok := bot.configProvider.(config.CacheInvalidator); ok {
+		cache.Invalidate(account, "")
+	}
+	return nil
+}
+
+// repoNames returns the names of repositories, without their owner
+func repoNames(repos []*github.Repository) []string {
+	names := make([]string, 0, len(repos))
+	for _, repo := range repos {
+		names = append(names, repo.GetName())
+	}
+	return names
+}
@@ -0,0 +1,158 @@
+package bot
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/google/go-github/v57/github"
+
+	"cyclone/internal/config"
+	"cyclone/internal/jobs"
+)
+
+// Kinds of jobs webhooks are turned into
+const (
+	jobPullRequest       = "pull_request"
+	jobIncrementalReview = "incremental_review"
+	jobCommand           = "command"
+	jobThreadReply       = "thread_reply"
+	jobInstallation      = "installation"
+)
+
+// pullRequestJob is a pull_request action that may trigger a review
+type pullRequestJob struct {
+	Action string              `json:"action"`
+	Repo   *github.Repository  `json:"repository"`
+	PR     *github.PullRequest `json:"pull_request"`
+}
+
+// incrementalReviewJob reviews the commits pushed to a PR since its last review
+type incrementalReviewJob struct {
+	Repo *github.Repository  `json:"repository"`
+	PR   *github.PullRequest `json:"pull_request"`
+}
+
+// commandJob is a /cyclone command with the commandContext it was issued in
+type commandJob struct {
+	Verb            string             `json:"verb"`
+	Args            string             `json:"args"`
+	Repo            *github.Repository `json:"repository"`
+	PRNumber        int                `json:"pr_number"`
+	CommentID       int64              `json:"comment_id"`
+	ThreadID        int64              `json:"thread_id"`
+	Author          string             `json:"author"`
+	IsReviewComment bool               `json:"is_review_comment"`
+}
+
+// threadReplyJob is a reply in a review thread that Cyclone may answer
+type threadReplyJob struct {
+	Repo  *github.Repository         `json:"repository"`
+	PR    *github.PullRequest        `json:"pull_request"`
+	Reply *github.PullRequestComment `json:"reply"`
+}
+
+// installationJob is an installation or installation_repositories event
+type installationJob struct {
+	Event   string          `json:"event"`
+	Payload *WebhookPayload `json:"payload"`
+}
+
+// newJobQueue creates the job queue selected by JOB_BACKEND
+func newJobQueue(cfg *config.Config) jobs.Queue {
+	if cfg.JobBackend == config.JobBackendSupabase {
+		return jobs.NewSupabaseQueue(cfg)
+	}
+	return jobs.NewMemoryQueue()
+}
+
+// handleJobs registers the handlers of the jobs webhooks are turned into
+func (bot *CycloneBot) handleJobs() {
+	bot.jobs.Handle(jobPullRequest, func(ctx context.Context, job *jobs.Job) error {
+		var p pullRequestJob
+		if err := job.Decode(&p); err != nil {
+			return err
+		}
+		return bot.HandlePullRequestEvent(ctx, p.Action, p.Repo, p.PR, job.InstallationID)
+	})
+
+	bot.jobs.Handle(jobIncrementalReview, func(ctx context.Context, job *jobs.Job) error {
+		var p incrementalReviewJob
+		if err := job.Decode(&p); err != nil {
+			return err
+		}
+		return bot.ProcessIncrementalReview(ctx, p.Repo, p.PR, job.InstallationID)
+	})
+
+	bot.jobs.Handle(jobCommand, func(ctx context.Context, job *jobs.Job) error {
+		var p commandJob
+		if err := job.Decode(&p); err != nil {
+			return err
+		}
+		cmd := &command{verb: p.Verb, args: p.Args}
+		return bot.RunCommand(ctx, cmd, commandContext{
+			repo:            p.Repo,
+			prNumber:        p.PRNumber,
+			installationID:  job.InstallationID,
+			commentID:       p.CommentID,
+			threadID:        p.ThreadID,
+			author:          p.Author,
+			isReviewComment: p.IsReviewComment,
+		})
+	})
+
+	bot.jobs.Handle(jobThreadReply, func(ctx context.Context, job *jobs.Job) error {
+		var p threadReplyJob
+		if err := job.Decode(&p); err != nil {
+			return err
+		}
+		return bot.ProcessThreadReply(ctx, p.Repo, p.PR, p.Reply, job.InstallationID)
+	})
+
+	bot.jobs.Handle(jobInstallation, func(ctx context.Context, job *jobs.Job) error {
+		var p installationJob
+		if err := job.Decode(&p); err != nil {
+			return err
+		}
+		return bot.ProcessInstallationEvent(ctx, p.Event, p.Payload)
+	})
+}
+
+// enqueue queues a job of a kind for an installation
+func (bot *CycloneBot) enqueue(ctx context.Context, kind string, installationID int64, payload any) error {
+	job, err := jobs.New(kind, installationID, payload)
+	if err != nil {
+		return err
+	}
+	return bot.jobs.Enqueue(ctx, job)
+}
+
+// incrementalReviewKey identifies the pending incremental review of a PR
+func incrementalReviewKey(key prKey) string {
+	return jobIncrementalReview + ":" + key.String()
+}
+
+// ScheduleIncrementalReview debounces pushes to a PR so a burst of pushes results in one
+// review: every push replaces the pending review job of the PR and delays it again
+func (bot *CycloneBot) ScheduleIncrementalReview(ctx context.Context, repo *github.Repository, pr *github.PullRequest, installationID int64) error {
+	key := newPRKey(repo, pr)
+	log.Printf("Scheduling incremental review for %s in %s", key, config.SYNCHRONIZE_DEBOUNCE)
+
+	job, err := jobs.New(jobIncrementalReview, installationID, incrementalReviewJob{Repo: repo, PR: pr})
+	if err != nil {
+		return err
+	}
+	job.Key = incrementalReviewKey(key)
+	job.RunAt = time.Now().Add(config.SYNCHRONIZE_DEBOUNCE).UTC()
+
+	if err := bot.jobs.Enqueue(ctx, job); err != nil {
+		return fmt.Errorf("failed to schedule incremental review for %s: %w", key, err)
+	}
+	return nil
+}
+
+// RunJobs runs the queued jobs until ctx is done
+func (bot *CycloneBot) RunJobs(ctx context.Context) {
+	bot.jobs.Run(ctx)
+}
@@ -0,0 +1,70 @@
+package bot
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+
+	"github.com/google/go-github/v57/github"
+
+	"cyclone/internal/config"
+	"cyclone/internal/review"
+)
+
+// repositoryConfig returns the configuration a PR is reviewed with: the central configuration
+// of its repository with the .cyclone.yml of the base branch on top. It returns nil without
+// an error if the repository isn't configured centrally, which a .cyclone.yml can't change,
+// and an error if the central configuration couldn't be looked up, so the job is retried.
+// An invalid .cyclone.yml is reported on the PR and ignored.
+func (bot *CycloneBot) repositoryConfig(ctx context.Context, githubClient *review.GitHubClient, repo *github.Repository, pr *github.PullRequest, installationID int64) (*config.RepositoryConfig, error) {
+	owner, repoName := repo.GetOwner().GetLogin(), repo.GetName()
+
+	repoConfig, err := bot.configProvider.GetRepositoryConfig(ctx, owner, repoName, installationID)
+	if errors.Is(err, config.ErrNotConfigured) {
+		log.Printf("Repository %s/%s not found in configuration - skipping: %s", owner, repoName, err)
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to look up configuration of %s/%s: %w", owner, repoName, err)
+	}
+
+	baseRef := pr.GetBase().GetRef()
+	content, err := githubClient.GetFileContent(ctx, owner, repoName, config.REPO_CONFIG_FILE, baseRef)
+	if errors.Is(err, review.ErrFileNotFound) {
+		return repoConfig, nil
+	}
+	if err != nil {
+		log.Printf("Error reading %s of %s/%s, using the central configuration: %v", config.REPO_CONFIG_FILE, owner, repoName, err)
+		return repoConfig, nil
+	}
+
+	file, err := config.ParseRepoFile([]byte(content))
+	if err != nil {
+		log.Printf("Invalid %s in %s/%s@%s: %v", config.REPO_CONFIG_FILE, owner, repoName, baseRef, err)
+		if bot.state.NewConfigError(newPRKey(repo, pr), err.Error()) {
+			if err := githubClient.PostComment(ctx, owner, repoName, pr.GetNumber(), configErrorMessage(baseRef, err)); err != nil {
+				log.Printf("Error reporting invalid %s: %v", config.REPO_CONFIG_FILE, err)
+			}
+		}
+		return repoConfig, nil
+	}
+
+	log.Printf("Using %s of %s/%s@%s", config.REPO_CONFIG_FILE, owner, repoName, baseRef)
+	return file.ApplyTo(repoConfig), nil
+}
+
+// configErrorMessage tells the author why the repository's .cyclone.yml was ignored
+func configErrorMessage(baseRef string, err error) string {
+	return fmt.Sprintf(`## 🌪️ Cyclone Notice
+
+⚠️ **Invalid %[1]s**
+
+The %[1]s on `+"`%[2]s`"+` couldn't be read, so this PR is reviewed with the repository's central configuration:
+
+`+"```"+`
+%[3]v
+`+"```"+`
+
+Fix the file on `+"`%[2]s`"+` to apply it to the next review.`, "`"+config.REPO_CONFIG_FILE+"`", baseRef, err)
+}
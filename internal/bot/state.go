@@ -0,0 +1,77 @@
+package bot
+
+import (
+	"fmt"
+	"sync"
+
+	"github.com/google/go-github/v57/github"
+)
+
+// prKey identifies a pull request across repositories
+type prKey struct {
+	owner  string
+	repo   string
+	number int
+}
+
+func newPRKey(repo *github.Repository, pr *github.PullRequest) prKey {
+	return prKey{
+		owner:  repo.GetOwner().GetLogin(),
+		repo:   repo.GetName(),
+		number: pr.GetNumber(),
+	}
+}
+
+func (k prKey) String() string {
+	return fmt.Sprintf("%s/%s#%d", k.owner, k.repo, k.number)
+}
+
+// reviewState tracks per-PR review progress in memory
+type reviewState struct {
+	mu           sync.Mutex
+	lastReviewed map[prKey]string // head SHA of the last posted review
+	configErrors map[prKey]string // last .cyclone.yml error reported on the PR
+}
+
+func newReviewState() *reviewState {
+	return &reviewState{
+		lastReviewed: make(map[prKey]string),
+		configErrors: make(map[prKey]string),
+	}
+}
+
+// LastReviewedSHA returns the head SHA Cyclone last reviewed for a PR, if known
+func (s *reviewState) LastReviewedSHA(key prKey) string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.lastReviewed[key]
+}
+
+// MarkReviewed records the head SHA a review was posted for
+func (s *reviewState) MarkReviewed(key prKey, sha string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.lastReviewed[key] = sha
+}
+
+// NewConfigError records an error in the .cyclone.yml of a PR and reports whether it
+// differs from the one last reported, so every push doesn't repeat the same comment
+func (s *reviewState) NewConfigError(key prKey, message string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.configErrors[key] == message {
+		return false
+	}
+	s.configErrors[key] = message
+	return true
+}
+
+// Forget drops all state for a PR, e.g. once it is closed
+func (s *reviewState) Forget(key prKey) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.lastReviewed, key)
+	delete(s.configErrors, key)
+}
@@ -1,25 +1,36 @@
 package bot
 
 import (
+	"context"
 	"crypto/hmac"
+	"crypto/sha1"
 	"crypto/sha256"
 	"encoding/hex"
 	"encoding/json"
 	"io"
 	"log"
 	"net/http"
+	"strings"
 
 	"github.com/google/go-github/v57/github"
 )
 
 // WebhookPayload represents the GitHub webhook payload
 type WebhookPayload struct {
-	Action       string              `json:"action"`
-	PullRequest  *github.PullRequest `json:"pull_request"`
-	Repository   *github.Repository  `json:"repository"`
+	Action       string                     `json:"action"`
+	PullRequest  *github.PullRequest        `json:"pull_request"`
+	Repository   *github.Repository         `json:"repository"`
+	Issue        *github.Issue              `json:"issue"`   // issue_comment events
+	Comment      *github.PullRequestComment `json:"comment"` // issue_comment and pull_request_review_comment events
 	Installation *struct {
-		ID int64 `json:"id"`
+		ID      int64        `json:"id"`
+		Account *github.User `json:"account"`
 	} `json:"installation"`
+
+	// installation and installation_repositories events
+	Repositories        []*github.Repository `json:"repositories"`
+	RepositoriesAdded   []*github.Repository `json:"repositories_added"`
+	RepositoriesRemoved []*github.Repository `json:"repositories_removed"`
 }
 
 // handleWebhook processes incoming GitHub webhooks
@@ -36,13 +47,25 @@ func (bot *CycloneBot) handleWebhook(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if bot.config.GitHubWebhookSecret != "" {
-		signature := r.Header.Get("X-Hub-Signature-256")
-		if !bot.validateWebhookSignature(body, signature) {
-			log.Printf("Invalid webhook signature")
-			http.Error(w, "Unauthorized", http.StatusUnauthorized)
-			return
-		}
+	// GitHub Enterprise Server names itself, github.com doesn't
+	hostName := r.Header.Get("X-GitHub-Enterprise-Host")
+	if hostName == "" {
+		hostName = "github.com"
+	}
+	host, ok := bot.hostNamed(hostName)
+	if !ok {
+		log.Printf("Ignoring webhook from unknown GitHub instance %s", hostName)
+		http.Error(w, "Unknown GitHub instance", http.StatusBadRequest)
+		return
+	}
+	if version := r.Header.Get("X-GitHub-Enterprise-Version"); version != "" {
+		log.Printf("Webhook from %s (GitHub Enterprise Server %s)", hostName, version)
+	}
+
+	if host.webhookSecret != "" && !validateWebhookSignature(host.webhookSecret, body, r.Header, host.enterprise) {
+		log.Printf("Invalid webhook signature")
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
 	}
 
 	// Parse the webhook payload
@@ -53,6 +76,43 @@ func (bot *CycloneBot) handleWebhook(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// Get installation ID
+	var installationID int64
+	if payload.Installation != nil {
+		installationID = payload.Installation.ID
+	}
+
+	// Work is queued rather than done while GitHub waits for the response. If it can't be
+	// queued, the failed delivery can be redelivered from GitHub.
+	ctx := r.Context()
+	switch event := r.Header.Get("X-GitHub-Event"); event {
+	case "issue_comment", "pull_request_review_comment":
+		if err := bot.handleCommentEvent(ctx, event, &payload, installationID); err != nil {
+			log.Printf("Error queueing %s event: %v", event, err)
+			http.Error(w, "Failed to queue event", http.StatusInternalServerError)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+		return
+	case "installation", "installation_repositories":
+		if err := bot.enqueue(ctx, jobInstallation, installationID, installationJob{Event: event, Payload: &payload}); err != nil {
+			log.Printf("Error queueing %s event: %v", event, err)
+			http.Error(w, "Failed to queue event", http.StatusInternalServerError)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+
+	// Drop any tracked state and pending review once a PR is closed
+	if payload.Action == "closed" && payload.PullRequest != nil && payload.Repository != nil {
+		key := newPRKey(payload.Repository, payload.PullRequest)
+		bot.state.Forget(key)
+		if err := bot.jobs.Queue().Cancel(ctx, incrementalReviewKey(key)); err != nil {
+			log.Printf("Error canceling pending review of %s: %v", key, err)
+		}
+	}
+
 	// Only process specific actions that warrant a review
 	if !bot.shouldTriggerReview(payload.Action, payload.PullRequest) {
 		log.Printf("Ignoring action: %s for PR #%d", payload.Action, payload.PullRequest.GetNumber())
@@ -60,21 +120,103 @@ func (bot *CycloneBot) handleWebhook(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if isPaused(payload.PullRequest) {
+		log.Printf("Reviews are paused for PR #%d - ignoring action: %s", payload.PullRequest.GetNumber(), payload.Action)
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+
 	log.Printf("Processing PR #%d: %s", payload.PullRequest.GetNumber(), payload.Action)
 
-	// Get installation ID
-	var installationID int64
-	if payload.Installation != nil {
-		installationID = payload.Installation.ID
+	// Queue the PR to avoid blocking the webhook
+	job := pullRequestJob{Action: payload.Action, Repo: payload.Repository, PR: payload.PullRequest}
+	if err := bot.enqueue(ctx, jobPullRequest, installationID, job); err != nil {
+		log.Printf("Error queueing PR #%d: %v", payload.PullRequest.GetNumber(), err)
+		http.Error(w, "Failed to queue event", http.StatusInternalServerError)
+		return
 	}
 
-	// Process the PR in a goroutine to avoid blocking the webhook
-	go bot.ProcessPullRequest(payload.Repository, payload.PullRequest, installationID)
-
 	w.WriteHeader(http.StatusOK)
 }
 
-// shouldTriggerReview determines if we should review this PR based on action and state
+// HandlePullRequestEvent reviews a PR for a pull_request action, if the action is one of
+// the repository's triggers
+func (bot *CycloneBot) HandlePullRequestEvent(ctx context.Context, action string, repo *github.Repository, pr *github.PullRequest, installationID int64) error {
+	// New pushes are debounced and only the new commits get reviewed. The debounced review
+	// checks the triggers, so the configuration is only looked up once per push.
+	if action == "synchronize" {
+		return bot.ScheduleIncrementalReview(ctx, repo, pr, installationID)
+	}
+
+	githubClient, err := bot.createInstallationClient(ctx, repo, installationID)
+	if err != nil {
+		return err
+	}
+
+	repoConfig, err := bot.repositoryConfig(ctx, githubClient, repo, pr, installationID)
+	if err != nil {
+		return err
+	}
+	if repoConfig == nil {
+		return nil
+	}
+	if !repoConfig.TriggersOn(action) {
+		log.Printf("Action %s doesn't trigger reviews in %s - ignoring PR #%d", action, repo.GetFullName(), pr.GetNumber())
+		return nil
+	}
+
+	return bot.reviewPullRequest(ctx, githubClient, repo, pr, repoConfig)
+}
+
+// handleCommentEvent queues /cyclone commands found in newly created PR comments
+func (bot *CycloneBot) handleCommentEvent(ctx context.Context, event string, payload *WebhookPayload, installationID int64) error {
+	if payload.Action != "created" || payload.Comment == nil || payload.Repository == nil {
+		return nil
+	}
+
+	// Never react to bots, including Cyclone's own comments
+	if payload.Comment.GetUser().GetType() == "Bot" {
+		return nil
+	}
+
+	cmd, ok := parseCommand(payload.Comment.GetBody())
+	if !ok {
+		// Replies in review threads may be follow-ups to Cyclone's own comments
+		if event == "pull_request_review_comment" && payload.Comment.GetInReplyTo() != 0 && payload.PullRequest != nil {
+			job := threadReplyJob{Repo: payload.Repository, PR: payload.PullRequest, Reply: payload.Comment}
+			return bot.enqueue(ctx, jobThreadReply, installationID, job)
+		}
+		return nil
+	}
+
+	job := commandJob{
+		Verb:            cmd.verb,
+		Args:            cmd.args,
+		Repo:            payload.Repository,
+		CommentID:       payload.Comment.GetID(),
+		Author:          payload.Comment.GetUser().GetLogin(),
+		IsReviewComment: event == "pull_request_review_comment",
+	}
+
+	if job.IsReviewComment {
+		job.PRNumber = payload.PullRequest.GetNumber()
+		job.ThreadID = payload.Comment.GetID()
+		if root := payload.Comment.GetInReplyTo(); root != 0 {
+			job.ThreadID = root
+		}
+	} else {
+		// issue_comment events fire for issues too, only PR conversations are relevant
+		if payload.Issue == nil || !payload.Issue.IsPullRequest() {
+			return nil
+		}
+		job.PRNumber = payload.Issue.GetNumber()
+	}
+
+	return bot.enqueue(ctx, jobCommand, installationID, job)
+}
+
+// shouldTriggerReview determines if an action may review this PR based on action and state,
+// the repository's triggers decide whether it does (see HandlePullRequestEvent)
 func (bot *CycloneBot) shouldTriggerReview(action string, pr *github.PullRequest) bool {
 	// Skip draft PRs entirely
 	if pr.GetDraft() {
@@ -91,9 +233,12 @@ func (bot *CycloneBot) shouldTriggerReview(action string, pr *github.PullRequest
 		return true
 
 	case "synchronize":
-		// Only review new commits if PR is not draft and we haven't reviewed recently
-		// You might want to add additional logic here to avoid reviewing every commit
-		return false // For now, skip synchronize events
+		// Review the commits pushed since the last review (debounced)
+		return true
+
+	case "reopened":
+		// Only reviewed if the repository opts in via the triggers of its .cyclone.yml
+		return true
 
 	default:
 		// Skip all other actions (closed, edited, etc.)
@@ -101,17 +246,24 @@ func (bot *CycloneBot) shouldTriggerReview(action string, pr *github.PullRequest
 	}
 }
 
-func (bot *CycloneBot) validateWebhookSignature(payload []byte, signature string) bool {
+// validateWebhookSignature checks the HMAC signature of a webhook. Older GitHub Enterprise
+// Server versions only send the SHA-1 signature in X-Hub-Signature, which is accepted when
+// allowSHA1 is set for such hosts; github.com always sends X-Hub-Signature-256.
+func validateWebhookSignature(secret string, payload []byte, header http.Header, allowSHA1 bool) bool {
+	signature, newHash := header.Get("X-Hub-Signature-256"), sha256.New
+	if signature == "" && allowSHA1 {
+		signature, newHash = header.Get("X-Hub-Signature"), sha1.New
+	}
 	if signature == "" {
 		return false
 	}
 
-	// Remove 'sha256=' prefix
-	if len(signature) > 7 && signature[:7] == "sha256=" {
-		signature = signature[7:]
+	// Remove 'sha256=' or 'sha1=' prefix
+	if _, hash, ok := strings.Cut(signature, "="); ok {
+		signature = hash
 	}
 
-	mac := hmac.New(sha256.New, []byte(bot.config.GitHubWebhookSecret))
+	mac := hmac.New(newHash, []byte(secret))
 	mac.Write(payload)
 	expectedMAC := hex.EncodeToString(mac.Sum(nil))
 
@@ -0,0 +1,126 @@
+package config
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/sync/singleflight"
+)
+
+// CacheInvalidator is implemented by providers caching repository configurations
+type CacheInvalidator interface {
+	// Invalidate drops the cached configuration of a repository, or of all repositories of
+	// the organization if repoName is empty, and returns the number of entries dropped
+	Invalidate(orgName, repoName string) int
+}
+
+// CachingProvider caches the configurations of another ConfigProvider for a TTL. Repositories
+// that are not configured are cached too, so ignored repositories don't cost a lookup per
+// event; other errors are not cached. Concurrent lookups of the same repository are
+// coalesced into one.
+type CachingProvider struct {
+	next ConfigProvider
+	ttl  time.Duration
+
+	mu         sync.Mutex
+	entries    map[cacheKey]cacheEntry
+	generation uint64 // incremented by Invalidate, so lookups started before aren't cached
+	group      singleflight.Group
+}
+
+type cacheKey struct {
+	installationID int64
+	org            string
+	repo           string
+}
+
+type cacheEntry struct {
+	config  *RepositoryConfig // nil if the repository is not configured
+	err     error
+	expires time.Time
+}
+
+// NewCachingProvider caches the configurations of next for ttl
+func NewCachingProvider(next ConfigProvider, ttl time.Duration) *CachingProvider {
+	return &CachingProvider{
+		next:    next,
+		ttl:     ttl,
+		entries: make(map[cacheKey]cacheEntry),
+	}
+}
+
+// GetRepositoryConfig returns the cached configuration of a repository, looking it up once
+// it expired
+func (cp *CachingProvider) GetRepositoryConfig(ctx context.Context, orgName, repoName string, installationID int64) (*RepositoryConfig, error) {
+	key := cacheKey{installationID: installationID, org: strings.ToLower(orgName), repo: strings.ToLower(repoName)}
+
+	cp.mu.Lock()
+	entry, ok := cp.entries[key]
+	generation := cp.generation
+	cp.mu.Unlock()
+	if ok && time.Now().Before(entry.expires) {
+		return copyConfig(entry.config), entry.err
+	}
+
+	// Callers after an Invalidate don't join a lookup started before it
+	flightKey := fmt.Sprintf("%d/%s/%s/%d", key.installationID, key.org, key.repo, generation)
+	results := cp.group.DoChan(flightKey, func() (any, error) {
+		// The lookup is shared, so it isn't canceled with the caller that started it
+		lookupCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), SUPABASE_REQUEST_TIMEOUT)
+		defer cancel()
+
+		repoConfig, err := cp.next.GetRepositoryConfig(lookupCtx, orgName, repoName, installationID)
+		if err != nil && !errors.Is(err, ErrNotConfigured) {
+			return nil, err
+		}
+
+		cp.mu.Lock()
+		if cp.generation == generation {
+			cp.entries[key] = cacheEntry{config: repoConfig, err: err, expires: time.Now().Add(cp.ttl)}
+		}
+		cp.mu.Unlock()
+		return repoConfig, err
+	})
+
+	select {
+	case result := <-results:
+		if result.Val == nil {
+			return nil, result.Err
+		}
+		return copyConfig(result.Val.(*RepositoryConfig)), result.Err
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// Invalidate drops cached configurations, see CacheInvalidator
+func (cp *CachingProvider) Invalidate(orgName, repoName string) int {
+	cp.mu.Lock()
+	defer cp.mu.Unlock()
+
+	cp.generation++
+	dropped := 0
+	for key := range cp.entries {
+		if (orgName == "" || key.org == strings.ToLower(orgName)) && (repoName == "" || key.repo == strings.ToLower(repoName)) {
+			delete(cp.entries, key)
+			dropped++
+		}
+	}
+
+	log.Printf("Invalidated %d cached repository configuration(s) (org: %q, repo: %q)", dropped, orgName, repoName)
+	return dropped
+}
+
+// copyConfig copies a cached configuration so callers can't modify the cache
+func copyConfig(repoConfig *RepositoryConfig) *RepositoryConfig {
+	if repoConfig == nil {
+		return nil
+	}
+	c := *repoConfig
+	return &c
+}
@@ -0,0 +1,175 @@
+package config
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeProvider returns its configuration or error, optionally blocking lookups until
+// released
+type fakeProvider struct {
+	mu      sync.Mutex
+	config  *RepositoryConfig
+	err     error
+	lookups int
+	ctxErrs []error // errors of the lookup contexts once answered
+
+	started chan struct{} // receives a value per lookup if set
+	release chan struct{} // lookups wait for it if set
+}
+
+func (f *fakeProvider) GetRepositoryConfig(ctx context.Context, orgName, repoName string, installationID int64) (*RepositoryConfig, error) {
+	if f.started != nil {
+		f.started <- struct{}{}
+	}
+	if f.release != nil {
+		<-f.release
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.lookups++
+	f.ctxErrs = append(f.ctxErrs, ctx.Err())
+	if f.config == nil {
+		return nil, f.err
+	}
+	c := *f.config
+	return &c, f.err
+}
+
+func (f *fakeProvider) set(config *RepositoryConfig, err error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.config, f.err = config, err
+}
+
+func (f *fakeProvider) stats() (int, []error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.lookups, append([]error{}, f.ctxErrs...)
+}
+
+func TestCachingProviderCachesLookups(t *testing.T) {
+	fake := &fakeProvider{config: &RepositoryConfig{Name: "repo"}}
+	cp := NewCachingProvider(fake, time.Hour)
+	ctx := context.Background()
+
+	for i := 0; i < 2; i++ {
+		repoConfig, err := cp.GetRepositoryConfig(ctx, "Org", "Repo", 1)
+		if err != nil || repoConfig == nil || repoConfig.Name != "repo" {
+			t.Fatalf("got %+v, %v, want the configuration", repoConfig, err)
+		}
+		repoConfig.Name = "modified"
+	}
+	if lookups, _ := fake.stats(); lookups != 1 {
+		t.Errorf("got %d lookups, want 1", lookups)
+	}
+
+	// Not configured is cached, other errors aren't
+	fake.set(nil, ErrNotConfigured)
+	if _, err := cp.GetRepositoryConfig(ctx, "org", "unknown", 1); !errors.Is(err, ErrNotConfigured) {
+		t.Fatalf("got error %v, want ErrNotConfigured", err)
+	}
+	if _, err := cp.GetRepositoryConfig(ctx, "org", "unknown", 1); !errors.Is(err, ErrNotConfigured) {
+		t.Fatalf("got error %v, want the cached ErrNotConfigured", err)
+	}
+	fake.set(nil, errors.New("connection refused"))
+	for i := 0; i < 2; i++ {
+		if _, err := cp.GetRepositoryConfig(ctx, "org", "failing", 1); err == nil {
+			t.Fatalf("got no error, want the lookup's")
+		}
+	}
+	if lookups, _ := fake.stats(); lookups != 4 {
+		t.Errorf("got %d lookups, want 4", lookups)
+	}
+
+	if dropped := cp.Invalidate("ORG", ""); dropped != 2 {
+		t.Errorf("invalidated %d entries, want 2", dropped)
+	}
+}
+
+func TestCachingProviderLookupOutlivesCanceledCaller(t *testing.T) {
+	fake := &fakeProvider{
+		config:  &RepositoryConfig{Name: "repo"},
+		started: make(chan struct{}, 1),
+		release: make(chan struct{}),
+	}
+	cp := NewCachingProvider(fake, time.Hour)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	errs := make(chan error, 1)
+	go func() {
+		_, err := cp.GetRepositoryConfig(ctx, "org", "repo", 1)
+		errs <- err
+	}()
+	<-fake.started
+	cancel()
+	if err := <-errs; !errors.Is(err, context.Canceled) {
+		t.Fatalf("got error %v, want context.Canceled for the canceled caller", err)
+	}
+
+	// Another caller joins the lookup, which goes on without the canceled context
+	results := make(chan *RepositoryConfig, 1)
+	go func() {
+		repoConfig, _ := cp.GetRepositoryConfig(context.Background(), "org", "repo", 1)
+		results <- repoConfig
+	}()
+	close(fake.release)
+	if repoConfig := <-results; repoConfig == nil || repoConfig.Name != "repo" {
+		t.Fatalf("got %+v, want the configuration", repoConfig)
+	}
+
+	lookups, ctxErrs := fake.stats()
+	if lookups != 1 {
+		t.Errorf("got %d lookups, want 1", lookups)
+	}
+	if ctxErrs[0] != nil {
+		t.Errorf("lookup context was done: %v", ctxErrs[0])
+	}
+
+	// The result was cached
+	if _, err := cp.GetRepositoryConfig(context.Background(), "org", "repo", 1); err != nil {
+		t.Fatalf("GetRepositoryConfig: %v", err)
+	}
+	if lookups, _ := fake.stats(); lookups != 1 {
+		t.Errorf("got %d lookups, want 1 (cached)", lookups)
+	}
+}
+
+func TestCachingProviderInvalidateDuringLookup(t *testing.T) {
+	fake := &fakeProvider{
+		config:  &RepositoryConfig{Name: "old"},
+		started: make(chan struct{}, 2),
+		release: make(chan struct{}),
+	}
+	cp := NewCachingProvider(fake, time.Hour)
+
+	results := make(chan *RepositoryConfig, 1)
+	go func() {
+		repoConfig, _ := cp.GetRepositoryConfig(context.Background(), "org", "repo", 1)
+		results <- repoConfig
+	}()
+	<-fake.started
+
+	// The configuration changes while it's looked up
+	cp.Invalidate("org", "repo")
+	close(fake.release)
+	if repoConfig := <-results; repoConfig == nil || repoConfig.Name != "old" {
+		t.Fatalf("got %+v, want the configuration looked up", repoConfig)
+	}
+
+	fake.set(&RepositoryConfig{Name: "new"}, nil)
+	repoConfig, err := cp.GetRepositoryConfig(context.Background(), "org", "repo", 1)
+	if err != nil {
+		t.Fatalf("GetRepositoryConfig: %v", err)
+	}
+	if repoConfig.Name != "new" {
+		t.Errorf("got %q, the lookup from before the invalidation was cached", repoConfig.Name)
+	}
+	if lookups, _ := fake.stats(); lookups != 2 {
+		t.Errorf("got %d lookups, want 2", lookups)
+	}
+}
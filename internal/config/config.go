@@ -3,9 +3,12 @@ package config
 import (
 	"bufio"
 	"fmt"
+	"net/url"
 	"os"
+	"path"
 	"strconv"
 	"strings"
+	"time"
 )
 
 // Load loads both application and review configurations
@@ -19,34 +22,175 @@ func Load() (*Config, error) {
 		Port:                 getEnv("PORT", "8080"),
 		WebhookSecret:        os.Getenv("WEBHOOK_SECRET"),
 		AnthropicToken:       os.Getenv("ANTHROPIC_API_KEY"),
+		AIProvider:           getEnv("AI_PROVIDER", "anthropic"),
+		AIModel:              os.Getenv("AI_MODEL"),
+		AIFallbackModel:      os.Getenv("AI_FALLBACK_MODEL"),
+		OpenAIAPIKey:         os.Getenv("OPENAI_API_KEY"),
+		OpenAIBaseURL:        os.Getenv("OPENAI_BASE_URL"),
+		OllamaURL:            os.Getenv("OLLAMA_URL"),
 		GitHubAppID:          parseInt64Env("GITHUB_APP_ID"),
 		GitHubPrivateKeyPath: os.Getenv("GITHUB_PRIVATE_KEY_PATH"),
+		GitHubPrivateKey:     os.Getenv("GITHUB_PRIVATE_KEY"),
 		GitHubWebhookSecret:  os.Getenv("GITHUB_WEBHOOK_SECRET"),
+		GitHubAPIURL:         os.Getenv("GITHUB_API_URL"),
+		GitHubUploadURL:      os.Getenv("GITHUB_UPLOAD_URL"),
+		ConfigBackend:        getEnv("CONFIG_BACKEND", ConfigBackendSupabase),
+		ConfigFile:           getEnv("CONFIG_FILE", "review-config.json"),
+		DefaultPrecision:     ReviewPrecision(getEnv("DEFAULT_PRECISION", string(PrecisionMedium))),
+		AdminToken:           os.Getenv("ADMIN_TOKEN"),
 		SupabaseURL:          os.Getenv("SUPABASE_URL"),
 		SupabaseAPIKey:       os.Getenv("SUPABASE_API_KEY"),
 	}
 
-	// Validate required configuration
-	if cfg.GitHubToken == "" {
-		return nil, fmt.Errorf("GITHUB_TOKEN environment variable is required")
+	cacheTTL, err := parseDurationEnv("CONFIG_CACHE_TTL", CONFIG_CACHE_TTL)
+	if err != nil {
+		return nil, err
+	}
+	cfg.ConfigCacheTTL = cacheTTL
+
+	// Jobs are durable where there is a database to keep them in
+	defaultJobBackend := JobBackendMemory
+	if cfg.ConfigBackend == ConfigBackendSupabase {
+		defaultJobBackend = JobBackendSupabase
+	}
+	cfg.JobBackend = getEnv("JOB_BACKEND", defaultJobBackend)
+
+	for _, setting := range []struct {
+		key          string
+		value        *int
+		defaultValue int
+	}{
+		{"JOB_WORKERS", &cfg.JobWorkers, JOB_WORKERS},
+		{"JOB_INSTALLATION_CONCURRENCY", &cfg.JobInstallationConcurrency, JOB_INSTALLATION_CONCURRENCY},
+		{"JOB_MAX_ATTEMPTS", &cfg.JobMaxAttempts, JOB_MAX_ATTEMPTS},
+	} {
+		*setting.value, err = parsePositiveIntEnv(setting.key, setting.defaultValue)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if ghesURL := os.Getenv("GHES_URL"); ghesURL != "" {
+		cfg.Enterprise = &GitHubHost{
+			APIURL:         ghesURL,
+			UploadURL:      os.Getenv("GHES_UPLOAD_URL"),
+			Token:          os.Getenv("GHES_TOKEN"),
+			AppID:          parseInt64Env("GHES_APP_ID"),
+			PrivateKeyPath: os.Getenv("GHES_PRIVATE_KEY_PATH"),
+			PrivateKey:     os.Getenv("GHES_PRIVATE_KEY"),
+			WebhookSecret:  os.Getenv("GHES_WEBHOOK_SECRET"),
+		}
+	}
+
+	// Validate required configuration: a personal access token, a GitHub App, or both, for
+	// every GitHub instance
+	hosts := cfg.GitHubHosts()
+	if err := validateGitHubHost("GITHUB", hosts[0]); err != nil {
+		return nil, err
+	}
+	if cfg.Enterprise != nil {
+		if err := validateGitHubHost("GHES", *cfg.Enterprise); err != nil {
+			return nil, err
+		}
+		if hosts[0].Name() == cfg.Enterprise.Name() {
+			return nil, fmt.Errorf("GHES_URL must be a different GitHub instance than GITHUB_API_URL")
+		}
 	}
 
-	if cfg.AnthropicToken == "" {
+	if cfg.AnthropicToken == "" && cfg.AIProvider == "anthropic" {
 		return nil, fmt.Errorf("ANTHROPIC_API_KEY environment variable is required")
 	}
 
-	// Validate Supabase configuration
-	if cfg.SupabaseURL == "" {
-		return nil, fmt.Errorf("SUPABASE_URL environment variable is required")
+	switch cfg.DefaultPrecision {
+	case PrecisionMinor, PrecisionMedium, PrecisionStrict:
+	default:
+		return nil, fmt.Errorf("DEFAULT_PRECISION must be minor, medium or strict, got %q", cfg.DefaultPrecision)
 	}
 
-	if cfg.SupabaseAPIKey == "" {
-		return nil, fmt.Errorf("SUPABASE_ANON_KEY environment variable is required")
+	// Validate the configuration backend
+	switch cfg.ConfigBackend {
+	case ConfigBackendSupabase:
+	case ConfigBackendFile:
+		if cfg.ConfigFile == "" {
+			return nil, fmt.Errorf("CONFIG_FILE environment variable is required")
+		}
+	default:
+		return nil, fmt.Errorf("CONFIG_BACKEND must be %q or %q, got %q", ConfigBackendSupabase, ConfigBackendFile, cfg.ConfigBackend)
+	}
+
+	// Validate the job queue backend
+	switch cfg.JobBackend {
+	case JobBackendSupabase, JobBackendMemory:
+	default:
+		return nil, fmt.Errorf("JOB_BACKEND must be %q or %q, got %q", JobBackendSupabase, JobBackendMemory, cfg.JobBackend)
+	}
+
+	if cfg.ConfigBackend == ConfigBackendSupabase || cfg.JobBackend == JobBackendSupabase {
+		if cfg.SupabaseURL == "" {
+			return nil, fmt.Errorf("SUPABASE_URL environment variable is required")
+		}
+
+		if cfg.SupabaseAPIKey == "" {
+			return nil, fmt.Errorf("SUPABASE_API_KEY environment variable is required")
+		}
 	}
 
 	return cfg, nil
 }
 
+// GitHubHosts returns the GitHub instances Cyclone serves, the one configured with the
+// GITHUB_ variables first
+func (c *Config) GitHubHosts() []GitHubHost {
+	hosts := []GitHubHost{{
+		APIURL:         c.GitHubAPIURL,
+		UploadURL:      c.GitHubUploadURL,
+		Token:          c.GitHubToken,
+		AppID:          c.GitHubAppID,
+		PrivateKeyPath: c.GitHubPrivateKeyPath,
+		PrivateKey:     c.GitHubPrivateKey,
+		WebhookSecret:  c.GitHubWebhookSecret,
+	}}
+	if c.Enterprise != nil {
+		hosts = append(hosts, *c.Enterprise)
+	}
+	return hosts
+}
+
+// Name returns the host name of a GitHub instance, as in the URLs of its repositories and
+// the X-GitHub-Enterprise-Host header of its webhooks
+func (h GitHubHost) Name() string {
+	if h.APIURL == "" {
+		return "github.com"
+	}
+	u, err := url.Parse(h.APIURL)
+	if err != nil || u.Hostname() == "" {
+		return h.APIURL
+	}
+	// Instances with subdomain isolation serve their API from api.<host>
+	return strings.TrimPrefix(u.Hostname(), "api.")
+}
+
+// validateGitHubHost checks that a GitHub instance has a personal access token or a
+// complete GitHub App, given the prefix of its environment variables
+func validateGitHubHost(prefix string, h GitHubHost) error {
+	if h.APIURL != "" {
+		if u, err := url.Parse(h.APIURL); err != nil || u.Scheme == "" || u.Host == "" {
+			return fmt.Errorf("%s API URL must be an absolute URL, got %q", prefix, h.APIURL)
+		}
+	}
+
+	hasPrivateKey := h.PrivateKeyPath != "" || h.PrivateKey != ""
+	if (h.AppID != 0) != hasPrivateKey {
+		return fmt.Errorf("%[1]s_APP_ID and %[1]s_PRIVATE_KEY_PATH or %[1]s_PRIVATE_KEY environment variables must be set together", prefix)
+	}
+
+	if h.Token == "" && h.AppID == 0 {
+		return fmt.Errorf("%[1]s_TOKEN or %[1]s_APP_ID and a private key environment variables are required", prefix)
+	}
+
+	return nil
+}
+
 // GetRepositoryConfig finds the configuration for a specific repository
 // Returns nil if repository should be ignored (not in config)
 func (rc *ReviewConfig) GetRepositoryConfig(owner, repoName string) *RepositoryConfig {
@@ -61,6 +205,15 @@ func (rc *ReviewConfig) GetRepositoryConfig(owner, repoName string) *RepositoryC
 				}
 			}
 
+			// Look for a repository name pattern, e.g. "api-*"
+			for _, repo := range org.Repositories {
+				if isRepoPattern(repo.Name) {
+					if ok, _ := path.Match(repo.Name, repoName); ok {
+						return &repo
+					}
+				}
+			}
+
 			// Look for a wildcard/default repository config
 			for _, repo := range org.Repositories {
 				if repo.Name == "*" || repo.Name == "default" {
@@ -74,6 +227,12 @@ func (rc *ReviewConfig) GetRepositoryConfig(owner, repoName string) *RepositoryC
 	return nil
 }
 
+// isRepoPattern reports whether a configured repository name is a glob pattern, other than
+// the catch-all "*" which only applies when nothing else matches
+func isRepoPattern(name string) bool {
+	return name != "*" && strings.ContainsAny(name, "*?[")
+}
+
 // GetPrecisionGuidelines returns review guidelines based on precision level
 func GetPrecisionGuidelines(precision ReviewPrecision) string {
 	switch precision {
@@ -145,6 +304,32 @@ func getEnv(key, defaultValue string) string {
 	return defaultValue
 }
 
+// parseDurationEnv parses a duration such as "90s" or "5m" from an environment variable
+func parseDurationEnv(key string, defaultValue time.Duration) (time.Duration, error) {
+	value := os.Getenv(key)
+	if value == "" {
+		return defaultValue, nil
+	}
+	parsed, err := time.ParseDuration(value)
+	if err != nil || parsed < 0 {
+		return 0, fmt.Errorf("%s must be a duration such as 5m, got %q", key, value)
+	}
+	return parsed, nil
+}
+
+// parsePositiveIntEnv parses a number greater than zero from an environment variable
+func parsePositiveIntEnv(key string, defaultValue int) (int, error) {
+	value := os.Getenv(key)
+	if value == "" {
+		return defaultValue, nil
+	}
+	parsed, err := strconv.Atoi(value)
+	if err != nil || parsed < 1 {
+		return 0, fmt.Errorf("%s must be a number greater than zero, got %q", key, value)
+	}
+	return parsed, nil
+}
+
 func parseInt64Env(key string) int64 {
 	if value := os.Getenv(key); value != "" {
 		if parsed, err := strconv.ParseInt(value, 10, 64); err == nil {
@@ -0,0 +1,85 @@
+package config
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"gopkg.in/yaml.v3"
+)
+
+// FileProvider serves repository configurations from a local JSON or YAML file in the
+// ReviewConfig format, for teams running Cyclone without a Supabase project
+type FileProvider struct {
+	path   string
+	config *ReviewConfig
+}
+
+// NewFileProvider loads the review configuration file at path. Files ending in .yml or
+// .yaml are parsed as YAML, anything else as JSON.
+func NewFileProvider(path string) (ConfigProvider, error) {
+	reviewConfig, err := LoadReviewConfig(path)
+	if err != nil {
+		return nil, err
+	}
+
+	return &FileProvider{
+		path:   path,
+		config: reviewConfig,
+	}, nil
+}
+
+// NewConfigProvider creates the configuration provider selected by CONFIG_BACKEND
+func NewConfigProvider(cfg *Config) (ConfigProvider, error) {
+	switch cfg.ConfigBackend {
+	case ConfigBackendFile:
+		return NewFileProvider(cfg.ConfigFile)
+	case ConfigBackendSupabase:
+		provider, err := NewSupabaseProvider(cfg)
+		if err != nil || cfg.ConfigCacheTTL == 0 {
+			return provider, err
+		}
+		return NewCachingProvider(provider, cfg.ConfigCacheTTL), nil
+	default:
+		return nil, fmt.Errorf("unknown configuration backend %q", cfg.ConfigBackend)
+	}
+}
+
+// LoadReviewConfig reads and parses a review configuration file, rejecting unknown keys
+func LoadReviewConfig(path string) (*ReviewConfig, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read review configuration: %w", err)
+	}
+
+	var reviewConfig ReviewConfig
+	switch filepath.Ext(path) {
+	case ".yml", ".yaml":
+		decoder := yaml.NewDecoder(bytes.NewReader(data))
+		decoder.KnownFields(true)
+		err = decoder.Decode(&reviewConfig)
+	default:
+		decoder := json.NewDecoder(bytes.NewReader(data))
+		decoder.DisallowUnknownFields()
+		err = decoder.Decode(&reviewConfig)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse review configuration %s: %w", path, err)
+	}
+
+	return &reviewConfig, nil
+}
+
+// GetRepositoryConfig returns the configuration of a repository from the file. The
+// installation is ignored, the file configures repositories by organization name.
+func (fp *FileProvider) GetRepositoryConfig(ctx context.Context, orgName, repoName string, installationID int64) (*RepositoryConfig, error) {
+	repoConfig := fp.config.GetRepositoryConfig(orgName, repoName)
+	if repoConfig == nil {
+		return nil, fmt.Errorf("repository '%s/%s' in %s: %w", orgName, repoName, fp.path, ErrNotConfigured)
+	}
+
+	return repoConfig, nil
+}
@@ -2,10 +2,14 @@ package config
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"log"
 )
 
+// ErrNotConfigured is returned by a ConfigProvider for repositories Cyclone should not review
+var ErrNotConfigured = errors.New("not configured")
+
 type Installation struct {
 	ID             int64  `json:"id"`
 	InstallationID int64  `json:"installation_id"`
@@ -20,6 +24,17 @@ type DatabaseClient interface {
 	GetInstallationByInstallationID(ctx context.Context, installationID int64) (*Installation, error)
 	GetOrganizationByInstallationAndName(ctx context.Context, installationDBID int64, orgName string) ([]Organization, error)
 	GetRepositoryByOrganizationAndName(ctx context.Context, organizationID int64, repoName string) (*Repository, error)
+
+	// Reads and writes via PostgREST, queries are PostgREST filters such as "id=eq.1"
+	Select(ctx context.Context, table, query string, result any) error
+	Insert(ctx context.Context, table string, rows, result any) error
+	Upsert(ctx context.Context, table, onConflict string, rows, result any) error
+	Update(ctx context.Context, table, query string, values any) error
+	UpdateReturning(ctx context.Context, table, query string, values, result any) error
+	Delete(ctx context.Context, table, query string) error
+
+	// RPC calls a database function with named arguments
+	RPC(ctx context.Context, function string, args, result any) error
 }
 
 type Organization struct {
@@ -28,10 +43,17 @@ type Organization struct {
 }
 
 type Repository struct {
-	ID           int64  `json:"id"`
-	Name         string `json:"name"`
-	Precision    string `json:"precision"`
-	CustomPrompt string `json:"custom_prompt"`
+	ID            int64  `json:"id"`
+	Name          string `json:"name"`
+	Precision     string `json:"precision"`
+	CustomPrompt  string `json:"custom_prompt"`
+	Provider      string `json:"provider"`
+	Model         string `json:"model"`
+	FallbackModel string `json:"fallback_model"`
+	CheckPolicy   string `json:"check_policy"`
+	ReviewPolicy  string `json:"review_policy"`
+	ApproveClean  bool   `json:"approve_clean"`
+	MinSeverity   string `json:"min_severity"`
 }
 
 type SupabaseProvider struct {
@@ -73,8 +95,15 @@ func (sp *SupabaseProvider) GetRepositoryConfig(ctx context.Context, orgName, re
 
 	// Step 4: Return actual repository configuration from database
 	return &RepositoryConfig{
-		Name:         repository.Name,
-		Precision:    ReviewPrecision(repository.Precision),
-		CustomPrompt: repository.CustomPrompt,
+		Name:          repository.Name,
+		Precision:     ReviewPrecision(repository.Precision),
+		CustomPrompt:  repository.CustomPrompt,
+		Provider:      repository.Provider,
+		Model:         repository.Model,
+		FallbackModel: repository.FallbackModel,
+		CheckPolicy:   CheckPolicy(repository.CheckPolicy),
+		ReviewPolicy:  ReviewPolicy(repository.ReviewPolicy),
+		ApproveClean:  repository.ApproveClean,
+		MinSeverity:   repository.MinSeverity,
 	}, nil
 }
@@ -0,0 +1,155 @@
+package config
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// Provisioner keeps the installation, organization and repository rows in sync with the
+// installations of the GitHub App, so repositories don't have to be added by hand.
+// Removed installations and repositories are soft-deleted, keeping their configuration
+// for when they are added again.
+type Provisioner struct {
+	client           DatabaseClient
+	defaultPrecision ReviewPrecision
+}
+
+// NewProvisioner creates a provisioner writing to the Supabase database
+func NewProvisioner(cfg *Config) *Provisioner {
+	return &Provisioner{
+		client:           NewSupabaseClient(cfg.SupabaseURL, cfg.SupabaseAPIKey),
+		defaultPrecision: cfg.DefaultPrecision,
+	}
+}
+
+// AddInstallation records a new installation on an account along with the repositories it
+// was granted, restoring the rows of an earlier installation
+func (p *Provisioner) AddInstallation(ctx context.Context, installationID int64, account string, repos []string) error {
+	installation := map[string]any{"installation_id": installationID, "deleted_at": nil, "suspended_at": nil}
+	return p.addRepositories(ctx, installation, account, repos)
+}
+
+// AddRepositories records repositories added to an installation
+func (p *Provisioner) AddRepositories(ctx context.Context, installationID int64, account string, repos []string) error {
+	// Installations from before provisioning may not have a row yet
+	installation := map[string]any{"installation_id": installationID}
+	return p.addRepositories(ctx, installation, account, repos)
+}
+
+func (p *Provisioner) addRepositories(ctx context.Context, installation map[string]any, account string, repos []string) error {
+	var installations []Installation
+	if err := p.client.Upsert(ctx, "installation", "installation_id", installation, &installations); err != nil {
+		return fmt.Errorf("failed to provision installation %v: %w", installation["installation_id"], err)
+	}
+	if len(installations) == 0 {
+		return fmt.Errorf("failed to provision installation %v: no row returned", installation["installation_id"])
+	}
+
+	var organizations []Organization
+	organization := map[string]any{"installation_id": installations[0].ID, "name": account, "deleted_at": nil}
+	if err := p.client.Upsert(ctx, "organization", "installation_id,name", organization, &organizations); err != nil {
+		return fmt.Errorf("failed to provision organization %s: %w", account, err)
+	}
+	if len(organizations) == 0 {
+		return fmt.Errorf("failed to provision organization %s: no row returned", account)
+	}
+	organizationID := organizations[0].ID
+
+	if len(repos) == 0 {
+		return nil
+	}
+
+	rows := make([]map[string]any, 0, len(repos))
+	for _, repo := range repos {
+		rows = append(rows, map[string]any{"organization_id": organizationID, "name": repo, "deleted_at": nil})
+	}
+	if err := p.client.Upsert(ctx, "repository", "organization_id,name", rows, nil); err != nil {
+		return fmt.Errorf("failed to provision repositories of %s: %w", account, err)
+	}
+
+	// The upsert leaves the configuration of existing repositories alone, new ones get the default
+	query := fmt.Sprintf("organization_id=eq.%d&precision=is.null", organizationID)
+	if err := p.client.Update(ctx, "repository", query, map[string]any{"precision": p.defaultPrecision}); err != nil {
+		return fmt.Errorf("failed to set default precision of repositories of %s: %w", account, err)
+	}
+
+	log.Printf("Provisioned %d repositories of %s for installation %v", len(repos), account, installation["installation_id"])
+	return nil
+}
+
+// RemoveInstallation soft-deletes an uninstalled installation
+func (p *Provisioner) RemoveInstallation(ctx context.Context, installationID int64) error {
+	query := fmt.Sprintf("installation_id=eq.%d", installationID)
+	if err := p.client.Update(ctx, "installation", query, map[string]any{"deleted_at": now()}); err != nil {
+		return fmt.Errorf("failed to remove installation %d: %w", installationID, err)
+	}
+
+	log.Printf("Removed installation %d", installationID)
+	return nil
+}
+
+// SuspendInstallation marks an installation as suspended, or active again
+func (p *Provisioner) SuspendInstallation(ctx context.Context, installationID int64, suspended bool) error {
+	var suspendedAt any
+	if suspended {
+		suspendedAt = now()
+	}
+
+	query := fmt.Sprintf("installation_id=eq.%d", installationID)
+	if err := p.client.Update(ctx, "installation", query, map[string]any{"suspended_at": suspendedAt}); err != nil {
+		return fmt.Errorf("failed to update suspension of installation %d: %w", installationID, err)
+	}
+
+	log.Printf("Installation %d suspended: %t", installationID, suspended)
+	return nil
+}
+
+// RemoveRepositories soft-deletes repositories removed from an installation
+func (p *Provisioner) RemoveRepositories(ctx context.Context, installationID int64, account string, repos []string) error {
+	if len(repos) == 0 {
+		return nil
+	}
+
+	installation, err := p.client.GetInstallationByInstallationID(ctx, installationID)
+	if errors.Is(err, ErrNotConfigured) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to remove repositories of installation %d: %w", installationID, err)
+	}
+
+	organizations, err := p.client.GetOrganizationByInstallationAndName(ctx, installation.ID, account)
+	if errors.Is(err, ErrNotConfigured) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to remove repositories of %s: %w", account, err)
+	}
+
+	quoted := make([]string, 0, len(repos))
+	for _, repo := range repos {
+		quoted = append(quoted, `"`+repo+`"`)
+	}
+	for _, org := range organizations {
+		if !strings.EqualFold(org.Name, account) {
+			continue
+		}
+		query := fmt.Sprintf("organization_id=eq.%d&name=in.%s", org.ID, url.QueryEscape("("+strings.Join(quoted, ",")+")"))
+		if err := p.client.Update(ctx, "repository", query, map[string]any{"deleted_at": now()}); err != nil {
+			return fmt.Errorf("failed to remove repositories of %s: %w", account, err)
+		}
+	}
+
+	log.Printf("Removed %d repositories of %s from installation %d", len(repos), account, installationID)
+	return nil
+}
+
+// now returns the current time as a PostgreSQL timestamp
+func now() string {
+	return time.Now().UTC().Format(time.RFC3339)
+}
@@ -0,0 +1,203 @@
+package config
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"path"
+	"slices"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// RepoFileConfig is the review configuration a repository keeps in its .cyclone.yml.
+// Fields left out of the file are nil and keep the value of the central configuration.
+type RepoFileConfig struct {
+	Precision    *ReviewPrecision `yaml:"precision"`
+	CustomPrompt *string          `yaml:"custom_prompt"`
+	Model        *string          `yaml:"model"`
+	MinSeverity  *string          `yaml:"min_severity"`
+	Triggers     []string         `yaml:"triggers"`
+
+	Paths struct {
+		Include []string `yaml:"include"`
+		Exclude []string `yaml:"exclude"`
+	} `yaml:"paths"`
+
+	Limits struct {
+		MaxFiles        *int `yaml:"max_files"`
+		MaxAdditions    *int `yaml:"max_additions"`
+		MaxTotalChanges *int `yaml:"max_total_changes"`
+	} `yaml:"limits"`
+}
+
+// severityNames are the severities min_severity accepts, mirroring review.Severity
+var severityNames = []string{"nit", "suggestion", "question", "issue", "blocking"}
+
+// ParseRepoFile parses and validates a .cyclone.yml. Unknown keys are errors, so typos
+// don't silently fall back to the central configuration.
+func ParseRepoFile(data []byte) (*RepoFileConfig, error) {
+	var file RepoFileConfig
+	decoder := yaml.NewDecoder(bytes.NewReader(data))
+	decoder.KnownFields(true)
+	if err := decoder.Decode(&file); err != nil && !errors.Is(err, io.EOF) {
+		return nil, err
+	}
+
+	var problems []string
+	if file.Precision != nil {
+		switch *file.Precision {
+		case PrecisionMinor, PrecisionMedium, PrecisionStrict:
+		default:
+			problems = append(problems, fmt.Sprintf("precision: %q is not one of minor, medium, strict", *file.Precision))
+		}
+	}
+	if file.MinSeverity != nil && !slices.Contains(severityNames, strings.ToLower(*file.MinSeverity)) {
+		problems = append(problems, fmt.Sprintf("min_severity: %q is not one of %s", *file.MinSeverity, strings.Join(severityNames, ", ")))
+	}
+	for _, trigger := range file.Triggers {
+		if !slices.Contains(AllTriggers, trigger) {
+			problems = append(problems, fmt.Sprintf("triggers: %q is not one of %s", trigger, strings.Join(AllTriggers, ", ")))
+		}
+	}
+	for _, pattern := range append(append([]string{}, file.Paths.Include...), file.Paths.Exclude...) {
+		if !validGlob(pattern) {
+			problems = append(problems, fmt.Sprintf("paths: %q is not a valid glob", pattern))
+		}
+	}
+	for name, limit := range map[string]*int{
+		"max_files":         file.Limits.MaxFiles,
+		"max_additions":     file.Limits.MaxAdditions,
+		"max_total_changes": file.Limits.MaxTotalChanges,
+	} {
+		if limit != nil && *limit <= 0 {
+			problems = append(problems, fmt.Sprintf("limits.%s: must be positive, got %d", name, *limit))
+		}
+	}
+
+	if len(problems) > 0 {
+		slices.Sort(problems)
+		return nil, errors.New(strings.Join(problems, "\n"))
+	}
+	return &file, nil
+}
+
+// ApplyTo returns a copy of the central configuration of a repository with the settings
+// of the file on top. Settings in the file win, lists replace rather than extend, and the
+// size limits can only be lowered below the global ones.
+func (f *RepoFileConfig) ApplyTo(base *RepositoryConfig) *RepositoryConfig {
+	merged := *base
+
+	if f.Precision != nil {
+		merged.Precision = *f.Precision
+	}
+	if f.CustomPrompt != nil {
+		merged.CustomPrompt = *f.CustomPrompt
+	}
+	if f.Model != nil {
+		merged.Model = *f.Model
+	}
+	if f.MinSeverity != nil {
+		merged.MinSeverity = *f.MinSeverity
+	}
+	if f.Triggers != nil {
+		merged.Triggers = f.Triggers
+	}
+	if f.Paths.Include != nil {
+		merged.IncludePaths = f.Paths.Include
+	}
+	if f.Paths.Exclude != nil {
+		merged.ExcludePaths = f.Paths.Exclude
+	}
+	if f.Limits.MaxFiles != nil {
+		merged.MaxFiles = *f.Limits.MaxFiles
+	}
+	if f.Limits.MaxAdditions != nil {
+		merged.MaxAdditions = *f.Limits.MaxAdditions
+	}
+	if f.Limits.MaxTotalChanges != nil {
+		merged.MaxTotalChanges = *f.Limits.MaxTotalChanges
+	}
+
+	return &merged
+}
+
+// Limits returns the size limits of a repository, the global ones lowered by its own
+func (rc *RepositoryConfig) Limits() (files, additions, totalChanges int) {
+	lower := func(global, own int) int {
+		if own > 0 && own < global {
+			return own
+		}
+		return global
+	}
+	return lower(MAX_FILES_FOR_REVIEW, rc.MaxFiles),
+		lower(MAX_ADDITIONS_FOR_REVIEW, rc.MaxAdditions),
+		lower(MAX_TOTAL_CHANGES, rc.MaxTotalChanges)
+}
+
+// ShouldReviewPath reports whether a file is reviewed under the repository's include and
+// exclude globs. Excludes win over includes.
+func (rc *RepositoryConfig) ShouldReviewPath(name string) bool {
+	for _, pattern := range rc.ExcludePaths {
+		if MatchGlob(pattern, name) {
+			return false
+		}
+	}
+	if len(rc.IncludePaths) == 0 {
+		return true
+	}
+	for _, pattern := range rc.IncludePaths {
+		if MatchGlob(pattern, name) {
+			return true
+		}
+	}
+	return false
+}
+
+// MatchGlob matches a slash-separated path against a glob, where "**" matches any number
+// of directories. Globs without a slash match the file name in any directory, so
+// "*.pb.go" matches generated files everywhere.
+func MatchGlob(pattern, name string) bool {
+	pattern = strings.TrimPrefix(pattern, "/")
+	if !strings.Contains(pattern, "/") {
+		ok, _ := path.Match(pattern, path.Base(name))
+		return ok
+	}
+	return matchSegments(strings.Split(pattern, "/"), strings.Split(name, "/"))
+}
+
+func matchSegments(pattern, name []string) bool {
+	for len(pattern) > 0 {
+		if pattern[0] == "**" {
+			for i := 0; i <= len(name); i++ {
+				if matchSegments(pattern[1:], name[i:]) {
+					return true
+				}
+			}
+			return false
+		}
+		if len(name) == 0 {
+			return false
+		}
+		if ok, _ := path.Match(pattern[0], name[0]); !ok {
+			return false
+		}
+		pattern, name = pattern[1:], name[1:]
+	}
+	return len(name) == 0
+}
+
+// validGlob reports whether every segment of a glob is well-formed
+func validGlob(pattern string) bool {
+	if pattern == "" {
+		return false
+	}
+	for _, segment := range strings.Split(pattern, "/") {
+		if _, err := path.Match(segment, ""); err != nil {
+			return false
+		}
+	}
+	return true
+}
@@ -4,7 +4,9 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"io"
 	"net/http"
+	"net/url"
 	"strings"
 )
 
@@ -20,15 +22,15 @@ func NewSupabaseClient(url, apiKey string) *SupabaseClient {
 	return &SupabaseClient{
 		url:    url,
 		apiKey: apiKey,
-		client: &http.Client{},
+		client: &http.Client{Timeout: SUPABASE_REQUEST_TIMEOUT},
 	}
 }
 
 // GetInstallationByInstallationID retrieves installation by GitHub installation ID
 func (s *SupabaseClient) GetInstallationByInstallationID(ctx context.Context, installationID int64) (*Installation, error) {
-	query := fmt.Sprintf("installation_id=eq.%d", installationID)
+	query := fmt.Sprintf("installation_id=eq.%d&deleted_at=is.null&suspended_at=is.null", installationID)
 
-	req, err := s.buildRequest("GET", "/rest/v1/installation", query, nil)
+	req, err := s.buildRequest(ctx, "GET", "/rest/v1/installation", query, nil)
 	if err != nil {
 		return nil, err
 	}
@@ -40,7 +42,7 @@ func (s *SupabaseClient) GetInstallationByInstallationID(ctx context.Context, in
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("installation not found: %d", installationID)
+		return nil, fmt.Errorf("failed to look up installation %d: status %d", installationID, resp.StatusCode)
 	}
 
 	var installations []Installation
@@ -49,7 +51,7 @@ func (s *SupabaseClient) GetInstallationByInstallationID(ctx context.Context, in
 	}
 
 	if len(installations) == 0 {
-		return nil, fmt.Errorf("installation not found: %d", installationID)
+		return nil, fmt.Errorf("installation %d: %w", installationID, ErrNotConfigured)
 	}
 
 	return &installations[0], nil
@@ -57,9 +59,9 @@ func (s *SupabaseClient) GetInstallationByInstallationID(ctx context.Context, in
 
 // GetOrganizationByInstallationAndName retrieves organization by installation and name
 func (s *SupabaseClient) GetOrganizationByInstallationAndName(ctx context.Context, installationDBID int64, orgName string) ([]Organization, error) {
-	query := fmt.Sprintf("installation_id=eq.%d", installationDBID)
+	query := fmt.Sprintf("installation_id=eq.%d&deleted_at=is.null", installationDBID)
 
-	req, err := s.buildRequest("GET", "/rest/v1/organization", query, nil)
+	req, err := s.buildRequest(ctx, "GET", "/rest/v1/organization", query, nil)
 	if err != nil {
 		return nil, err
 	}
@@ -71,7 +73,7 @@ func (s *SupabaseClient) GetOrganizationByInstallationAndName(ctx context.Contex
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("organization not found: %s", orgName)
+		return nil, fmt.Errorf("failed to look up organization %s: status %d", orgName, resp.StatusCode)
 	}
 
 	var organizations []Organization
@@ -80,7 +82,7 @@ func (s *SupabaseClient) GetOrganizationByInstallationAndName(ctx context.Contex
 	}
 
 	if len(organizations) == 0 {
-		return nil, fmt.Errorf("organization not found: %s", orgName)
+		return nil, fmt.Errorf("organization %s: %w", orgName, ErrNotConfigured)
 	}
 
 	return organizations, nil
@@ -88,9 +90,9 @@ func (s *SupabaseClient) GetOrganizationByInstallationAndName(ctx context.Contex
 
 // GetRepositoryByOrganizationAndName retrieves repository by organization and name
 func (s *SupabaseClient) GetRepositoryByOrganizationAndName(ctx context.Context, organizationID int64, repoName string) (*Repository, error) {
-	query := fmt.Sprintf("organization_id=eq.%d&name=eq.%s", organizationID, repoName)
+	query := fmt.Sprintf("organization_id=eq.%d&name=eq.%s&deleted_at=is.null", organizationID, url.QueryEscape(repoName))
 
-	req, err := s.buildRequest("GET", "/rest/v1/repository", query, nil)
+	req, err := s.buildRequest(ctx, "GET", "/rest/v1/repository", query, nil)
 	if err != nil {
 		return nil, err
 	}
@@ -102,7 +104,7 @@ func (s *SupabaseClient) GetRepositoryByOrganizationAndName(ctx context.Context,
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("repository not found: %s", repoName)
+		return nil, fmt.Errorf("failed to look up repository %s: status %d", repoName, resp.StatusCode)
 	}
 
 	var repositories []Repository
@@ -111,17 +113,109 @@ func (s *SupabaseClient) GetRepositoryByOrganizationAndName(ctx context.Context,
 	}
 
 	if len(repositories) == 0 {
-		return nil, fmt.Errorf("repository not found: %s", repoName)
+		return nil, fmt.Errorf("repository %s: %w", repoName, ErrNotConfigured)
 	}
 
 	return &repositories[0], nil
 }
 
+// Select decodes the rows of a table matching a PostgREST query, which may also order and
+// limit them, into result
+func (s *SupabaseClient) Select(ctx context.Context, table, query string, result any) error {
+	req, err := s.buildRequest(ctx, http.MethodGet, "/rest/v1/"+table, query, nil)
+	if err != nil {
+		return err
+	}
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to read %s: %w", table, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("failed to read %s: status %d", table, resp.StatusCode)
+	}
+
+	if err := json.NewDecoder(resp.Body).Decode(result); err != nil {
+		return fmt.Errorf("failed to decode %s rows: %w", table, err)
+	}
+	return nil
+}
+
+// Insert inserts rows into a table, decoding the inserted rows into result unless it is nil
+func (s *SupabaseClient) Insert(ctx context.Context, table string, rows, result any) error {
+	return s.write(ctx, http.MethodPost, table, "", "return=representation", rows, result)
+}
+
+// Upsert inserts rows into a table, merging them into the existing rows with the same
+// onConflict columns, and decodes the resulting rows into result unless it is nil. Columns
+// left out of rows keep their value in existing rows.
+func (s *SupabaseClient) Upsert(ctx context.Context, table, onConflict string, rows, result any) error {
+	query := "on_conflict=" + url.QueryEscape(onConflict)
+	return s.write(ctx, http.MethodPost, table, query, "resolution=merge-duplicates,return=representation", rows, result)
+}
+
+// Update sets values on the rows of a table matching a PostgREST filter query
+func (s *SupabaseClient) Update(ctx context.Context, table, query string, values any) error {
+	return s.write(ctx, http.MethodPatch, table, query, "return=minimal", values, nil)
+}
+
+// UpdateReturning is Update, decoding the changed rows into result. Filtering on the old
+// values makes it a compare-and-swap: no rows are returned if another writer came first.
+func (s *SupabaseClient) UpdateReturning(ctx context.Context, table, query string, values, result any) error {
+	return s.write(ctx, http.MethodPatch, table, query, "return=representation", values, result)
+}
+
+// Delete deletes the rows of a table matching a PostgREST filter query
+func (s *SupabaseClient) Delete(ctx context.Context, table, query string) error {
+	return s.write(ctx, http.MethodDelete, table, query, "return=minimal", nil, nil)
+}
+
+// RPC calls a Postgres function with args as its named arguments, decoding its return
+// value into result unless it is nil. The function runs in a single transaction.
+func (s *SupabaseClient) RPC(ctx context.Context, function string, args, result any) error {
+	return s.write(ctx, http.MethodPost, "rpc/"+function, "", "return=representation", args, result)
+}
+
+// write sends a PostgREST request changing a table
+func (s *SupabaseClient) write(ctx context.Context, method, table, query, prefer string, body, result any) error {
+	if method != http.MethodPost && query == "" {
+		// PostgREST would change every row of the table
+		return fmt.Errorf("refusing to %s all rows of %s", method, table)
+	}
+
+	req, err := s.buildRequest(ctx, method, "/rest/v1/"+table, query, body)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Prefer", prefer)
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to write %s: %w", table, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		message, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return fmt.Errorf("failed to write %s: status %d: %s", table, resp.StatusCode, strings.TrimSpace(string(message)))
+	}
+
+	if result != nil {
+		if err := json.NewDecoder(resp.Body).Decode(result); err != nil {
+			return fmt.Errorf("failed to decode %s rows: %w", table, err)
+		}
+	}
+
+	return nil
+}
+
 // buildRequest helper method for Supabase API requests
-func (s *SupabaseClient) buildRequest(method, path, query string, body interface{}) (*http.Request, error) {
-	url := s.url + path
+func (s *SupabaseClient) buildRequest(ctx context.Context, method, path, query string, body interface{}) (*http.Request, error) {
+	endpoint := s.url + path
 	if query != "" {
-		url += "?" + query
+		endpoint += "?" + query
 	}
 
 	var reqBody []byte
@@ -133,7 +227,7 @@ func (s *SupabaseClient) buildRequest(method, path, query string, body interface
 		}
 	}
 
-	req, err := http.NewRequest(method, url, strings.NewReader(string(reqBody)))
+	req, err := http.NewRequestWithContext(ctx, method, endpoint, strings.NewReader(string(reqBody)))
 	if err != nil {
 		return nil, err
 	}
@@ -1,5 +1,7 @@
 package config
 
+import "time"
+
 // Config holds our application configuration
 type Config struct {
 	GitHubToken    string
@@ -7,14 +9,71 @@ type Config struct {
 	WebhookSecret  string
 	AnthropicToken string
 
+	// AI providers: the default provider and model, and the settings of the optional
+	// OpenAI-compatible and Ollama backends
+	AIProvider      string
+	AIModel         string
+	AIFallbackModel string
+	OpenAIAPIKey    string
+	OpenAIBaseURL   string
+	OllamaURL       string
+
 	GitHubAppID          int64
 	GitHubPrivateKeyPath string
+	GitHubPrivateKey     string // inline PEM or base64 encoded PEM, used without GitHubPrivateKeyPath
 	GitHubWebhookSecret  string
+	GitHubAPIURL         string // API of a GitHub Enterprise Server, "" for github.com
+	GitHubUploadURL      string // upload API of a GitHub Enterprise Server, "" for GitHubAPIURL
+
+	// A GitHub Enterprise Server served alongside the instance above, nil if none
+	Enterprise *GitHubHost
+
+	// Where repository configurations come from, see ConfigBackendFile and ConfigBackendSupabase
+	ConfigBackend  string
+	ConfigFile     string
+	ConfigCacheTTL time.Duration // how long repository configurations are cached, 0 disables the cache
+
+	// Precision of repositories provisioned from installation webhooks
+	DefaultPrecision ReviewPrecision
+
+	// Bearer token of the admin endpoints, which are disabled without one
+	AdminToken string
+
+	// Where review jobs are queued, see JobBackendMemory and JobBackendSupabase, and how
+	// many run at once, overall and per installation
+	JobBackend                 string
+	JobWorkers                 int
+	JobInstallationConcurrency int
+	JobMaxAttempts             int
 
 	SupabaseURL    string
 	SupabaseAPIKey string
 }
 
+// GitHubHost holds how Cyclone connects to a GitHub instance, github.com or a GitHub
+// Enterprise Server
+type GitHubHost struct {
+	APIURL         string // "" for github.com
+	UploadURL      string // "" for APIURL
+	Token          string
+	AppID          int64
+	PrivateKeyPath string
+	PrivateKey     string
+	WebhookSecret  string
+}
+
+// Configuration backends selectable with CONFIG_BACKEND
+const (
+	ConfigBackendSupabase = "supabase" // repositories configured in Supabase (default)
+	ConfigBackendFile     = "file"     // repositories configured in a local JSON or YAML file
+)
+
+// Job queue backends selectable with JOB_BACKEND
+const (
+	JobBackendSupabase = "supabase" // jobs survive restarts (default with the Supabase configuration backend)
+	JobBackendMemory   = "memory"   // jobs are lost on restart (default with the file configuration backend)
+)
+
 // ReviewPrecision defines how strict the review should be
 type ReviewPrecision string
 
@@ -24,30 +83,171 @@ const (
 	PrecisionStrict ReviewPrecision = "strict"
 )
 
+// CheckPolicy defines when the Cyclone check run fails, so teams can make it a required check
+type CheckPolicy string
+
+const (
+	CheckPolicyBlocking CheckPolicy = "blocking" // fail on any blocking comment (default)
+	CheckPolicyIssue    CheckPolicy = "issue"    // fail on any blocking or issue comment
+	CheckPolicyNever    CheckPolicy = "never"    // never fail, the check is informational
+)
+
+// ReviewPolicy defines when Cyclone requests changes instead of only commenting
+type ReviewPolicy string
+
+const (
+	ReviewPolicyBlocking ReviewPolicy = "blocking" // request changes on any blocking comment (default)
+	ReviewPolicyIssue    ReviewPolicy = "issue"    // request changes on any blocking or issue comment
+	ReviewPolicyNever    ReviewPolicy = "never"    // never request changes, only comment
+)
+
 // RepositoryConfig holds configuration for a specific repository
 type RepositoryConfig struct {
-	Name         string          `json:"name"`
-	Precision    ReviewPrecision `json:"precision"`
-	CustomPrompt string          `json:"custom_prompt"`
+	Name          string          `json:"name" yaml:"name"`
+	Precision     ReviewPrecision `json:"precision" yaml:"precision"`
+	CustomPrompt  string          `json:"custom_prompt" yaml:"custom_prompt"`
+	Provider      string          `json:"provider" yaml:"provider"`             // AI provider, empty for the default
+	Model         string          `json:"model" yaml:"model"`                   // model of the provider, empty for its default
+	FallbackModel string          `json:"fallback_model" yaml:"fallback_model"` // model of the provider tried when Model keeps failing
+	CheckPolicy   CheckPolicy     `json:"check_policy" yaml:"check_policy"`     // when the check run fails, empty for CheckPolicyBlocking
+	ReviewPolicy  ReviewPolicy    `json:"review_policy" yaml:"review_policy"`   // when changes are requested, empty for ReviewPolicyBlocking
+	ApproveClean  bool            `json:"approve_clean" yaml:"approve_clean"`   // approve PRs with nothing above a nit, opt-in
+	MinSeverity   string          `json:"min_severity" yaml:"min_severity"`     // drop comments below this severity, empty keeps all
+
+	// Set by the repository's .cyclone.yml only
+	IncludePaths    []string `json:"-" yaml:"-"` // review only files matching one of these globs, empty for all
+	ExcludePaths    []string `json:"-" yaml:"-"` // never review files matching one of these globs
+	MaxFiles        int      `json:"-" yaml:"-"` // lower size limits than the global ones, 0 for the global limit
+	MaxAdditions    int      `json:"-" yaml:"-"`
+	MaxTotalChanges int      `json:"-" yaml:"-"`
+	Triggers        []string `json:"-" yaml:"-"` // pull_request actions that start a review, empty for DefaultTriggers
+}
+
+// Pull request actions that can start a review, the ones reviewed by default come first
+var (
+	DefaultTriggers = []string{"opened", "ready_for_review", "synchronize"}
+	AllTriggers     = []string{"opened", "ready_for_review", "synchronize", "reopened"}
+)
+
+// TriggersOn reports whether a pull_request action starts a review of the repository
+func (rc *RepositoryConfig) TriggersOn(action string) bool {
+	triggers := rc.Triggers
+	if len(triggers) == 0 {
+		triggers = DefaultTriggers
+	}
+	for _, t := range triggers {
+		if t == action {
+			return true
+		}
+	}
+	return false
 }
 
 // OrganizationConfig holds configuration for an entire organization
 type OrganizationConfig struct {
-	Name         string             `json:"name"`
-	Repositories []RepositoryConfig `json:"repositories"`
+	Name         string             `json:"name" yaml:"name"`
+	Repositories []RepositoryConfig `json:"repositories" yaml:"repositories"`
 }
 type ReviewConfig struct {
-	Organizations []OrganizationConfig `json:"organizations"`
+	Organizations []OrganizationConfig `json:"organizations" yaml:"organizations"`
 }
 
 // Constants for PR size limits
 const (
-	// Hard limits for PR review
-	MAX_FILES_FOR_REVIEW     = 25   // Skip review if more files changed
-	MAX_ADDITIONS_FOR_REVIEW = 800  // Skip review if more lines added
-	MAX_TOTAL_CHANGES        = 1200 // Skip review if total changes exceed this
+	// Hard limits for PR review - a cost ceiling, PRs below it that don't fit into a
+	// single review are reviewed in chunks (see CHUNK_TOKEN_BUDGET)
+	MAX_FILES_FOR_REVIEW     = 300   // Skip review if more files changed
+	MAX_ADDITIONS_FOR_REVIEW = 15000 // Skip review if more lines added
+	MAX_TOTAL_CHANGES        = 20000 // Skip review if total changes exceed this
 
 	// Warning thresholds (still review, but warn)
 	WARN_FILES_THRESHOLD     = 20
 	WARN_ADDITIONS_THRESHOLD = 400
 )
+
+// Constants for looking up repository configurations
+const (
+	// Default of CONFIG_CACHE_TTL, how long configurations and "not configured" answers
+	// of the central configuration are cached
+	CONFIG_CACHE_TTL = 5 * time.Minute
+
+	// Timeout of every request to Supabase
+	SUPABASE_REQUEST_TIMEOUT = 10 * time.Second
+)
+
+// Constants for the job queue
+const (
+	// Defaults of JOB_WORKERS, JOB_INSTALLATION_CONCURRENCY and JOB_MAX_ATTEMPTS: jobs run
+	// at once, jobs of a single installation run at once, and attempts before a job is
+	// moved to the dead letters
+	JOB_WORKERS                  = 4
+	JOB_INSTALLATION_CONCURRENCY = 2
+	JOB_MAX_ATTEMPTS             = 5
+
+	// Exponential backoff between attempts of a failed job
+	JOB_RETRY_BASE_DELAY = 30 * time.Second
+	JOB_RETRY_MAX_DELAY  = 30 * time.Minute
+
+	// How often the queue is checked for jobs that became due, e.g. debounced reviews
+	JOB_POLL_INTERVAL = 5 * time.Second
+
+	// A job running longer than this is canceled and retried
+	JOB_TIMEOUT = 15 * time.Minute
+
+	// How long webhook requests being answered are waited for on shutdown
+	SHUTDOWN_TIMEOUT = 30 * time.Second
+)
+
+// Constants for GitHub App authentication
+const (
+	// Installation access tokens last an hour and are refreshed this long before they expire
+	INSTALLATION_TOKEN_REFRESH_MARGIN = 5 * time.Minute
+
+	// How often the private key file is checked for a rotated key
+	PRIVATE_KEY_POLL_INTERVAL = 30 * time.Second
+)
+
+// Constants for the configuration kept in the reviewed repository
+const (
+	// Read from the base branch of a PR, so a PR can't change the rules it is reviewed by
+	REPO_CONFIG_FILE = ".cyclone.yml"
+)
+
+// Constants for incremental reviews on pushed commits
+const (
+	// Wait this long after the last push before reviewing, so a burst of
+	// (force-)pushes results in a single incremental review
+	SYNCHRONIZE_DEBOUNCE = 30 * time.Second
+)
+
+// Constants for reviewing large PRs in chunks
+const (
+	// Diffs estimated above this many tokens are split into chunks that are reviewed
+	// separately and merged by a final synthesis call
+	CHUNK_TOKEN_BUDGET = 30000
+
+	// Maximum number of chunks of a single PR reviewed concurrently
+	MAX_PARALLEL_CHUNKS = 4
+)
+
+// Constants for retrying failed AI requests
+const (
+	// Retries of a transient failure (429, 529, 5xx, timeouts) before giving up on a model
+	MAX_AI_RETRIES = 3
+
+	// Exponential backoff between retries, the delay window doubles with every attempt
+	AI_RETRY_BASE_DELAY = 2 * time.Second
+	AI_RETRY_MAX_DELAY  = 60 * time.Second
+)
+
+// Constants for the code around the changes sent along with the diff
+const (
+	// Estimated tokens of surrounding code added to a review prompt
+	CONTEXT_TOKEN_BUDGET = 20000
+
+	// Changed files up to this many estimated tokens are included whole
+	MAX_FULL_FILE_TOKENS = 4000
+
+	// Files of a Go package searched for definitions the changes refer to
+	MAX_CONTEXT_PACKAGE_FILES = 30
+)
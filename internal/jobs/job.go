@@ -0,0 +1,131 @@
+package jobs
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// ErrNotFound is returned for jobs that don't exist, or not in the expected status
+var ErrNotFound = errors.New("job not found")
+
+// Status is the stage of a job in the queue
+type Status string
+
+const (
+	StatusQueued  Status = "queued"  // waiting to run at RunAt
+	StatusRunning Status = "running" // claimed by a worker
+	StatusDead    Status = "dead"    // failed permanently or too often, kept for inspection
+)
+
+// Job is a unit of work run by a Pool, e.g. reviewing a PR. Finished jobs are removed
+// from the queue.
+type Job struct {
+	ID             string          `json:"id"`
+	Kind           string          `json:"kind"`          // selects the handler, see Pool.Handle
+	Key            string          `json:"key,omitempty"` // a queued job with the same key is replaced
+	InstallationID int64           `json:"installation_id"`
+	Payload        json.RawMessage `json:"payload"`
+	Status         Status          `json:"status"`
+	Attempts       int             `json:"attempts"`
+	MaxAttempts    int             `json:"max_attempts"`
+	LastError      string          `json:"last_error,omitempty"`
+	RunAt          time.Time       `json:"run_at"`
+	CreatedAt      time.Time       `json:"created_at"`
+	UpdatedAt      time.Time       `json:"updated_at"`
+}
+
+// New creates a job of a kind for an installation, due now, with the JSON encoding of
+// payload
+func New(kind string, installationID int64, payload any) (*Job, error) {
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode %s job: %w", kind, err)
+	}
+
+	id := make([]byte, 16)
+	if _, err := rand.Read(id); err != nil {
+		return nil, fmt.Errorf("failed to create job ID: %w", err)
+	}
+
+	now := time.Now().UTC()
+	return &Job{
+		ID:             hex.EncodeToString(id),
+		Kind:           kind,
+		InstallationID: installationID,
+		Payload:        data,
+		Status:         StatusQueued,
+		RunAt:          now,
+		CreatedAt:      now,
+		UpdatedAt:      now,
+	}, nil
+}
+
+// Decode decodes the payload of a job
+func (j *Job) Decode(payload any) error {
+	if err := json.Unmarshal(j.Payload, payload); err != nil {
+		return Permanent(fmt.Errorf("failed to decode %s job %s: %w", j.Kind, j.ID, err))
+	}
+	return nil
+}
+
+// Queue stores jobs until a Pool runs them
+type Queue interface {
+	// Enqueue adds a job. If the job has a key, it replaces the payload and due time of a
+	// queued job with the same key instead, taking over its ID.
+	Enqueue(ctx context.Context, job *Job) error
+
+	// Claim marks the next due job as running, counting the attempt, and returns it, or nil
+	// if no job is due. Jobs of the installations in busy are left queued.
+	Claim(ctx context.Context, busy []int64) (*Job, error)
+
+	// Complete removes a job that ran successfully
+	Complete(ctx context.Context, job *Job) error
+
+	// Retry queues a failed job again, to run at runAt
+	Retry(ctx context.Context, job *Job, runAt time.Time, cause error) error
+
+	// Bury moves a failed job to the dead letters
+	Bury(ctx context.Context, job *Job, cause error) error
+
+	// Cancel removes the queued jobs with a key
+	Cancel(ctx context.Context, key string) error
+
+	// List returns up to limit jobs in a status, the oldest first
+	List(ctx context.Context, status Status, limit int) ([]Job, error)
+
+	// Requeue queues a dead job again with fresh attempts, ErrNotFound if there is no
+	// dead job with the ID
+	Requeue(ctx context.Context, id string) error
+
+	// Recover queues the jobs left running by a previous process again and returns their
+	// number. Cyclone runs as a single process, so running jobs at startup were interrupted.
+	Recover(ctx context.Context) (int, error)
+}
+
+// permanentError marks errors that retrying won't fix
+type permanentError struct {
+	err error
+}
+
+func (e *permanentError) Error() string { return e.err.Error() }
+func (e *permanentError) Unwrap() error { return e.err }
+
+// Permanent marks an error of a handler as permanent, so the job goes to the dead letters
+// without being retried
+func Permanent(err error) error {
+	if err == nil {
+		return nil
+	}
+	return &permanentError{err: err}
+}
+
+// IsPermanent reports whether an error was marked with Permanent
+func IsPermanent(err error) bool {
+	var permanent *permanentError
+	return errors.As(err, &permanent)
+}
@@ -0,0 +1,159 @@
+package jobs
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"sync"
+	"time"
+)
+
+// MemoryQueue keeps jobs in memory. They are lost on restart, use SupabaseQueue to keep them.
+type MemoryQueue struct {
+	mu   sync.Mutex
+	jobs map[string]*Job
+}
+
+// NewMemoryQueue creates an empty in-memory queue
+func NewMemoryQueue() *MemoryQueue {
+	return &MemoryQueue{jobs: make(map[string]*Job)}
+}
+
+// Enqueue adds a job, see Queue
+func (q *MemoryQueue) Enqueue(ctx context.Context, job *Job) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	if job.Key != "" {
+		for _, queued := range q.jobs {
+			if queued.Key == job.Key && queued.Status == StatusQueued {
+				queued.Payload, queued.RunAt, queued.UpdatedAt = job.Payload, job.RunAt, time.Now().UTC()
+				job.ID = queued.ID
+				return nil
+			}
+		}
+	}
+
+	stored := *job
+	q.jobs[job.ID] = &stored
+	return nil
+}
+
+// Claim marks the next due job as running, see Queue
+func (q *MemoryQueue) Claim(ctx context.Context, busy []int64) (*Job, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	now := time.Now()
+	var next *Job
+	for _, job := range q.jobs {
+		if job.Status != StatusQueued || job.RunAt.After(now) || slices.Contains(busy, job.InstallationID) {
+			continue
+		}
+		if next == nil || job.RunAt.Before(next.RunAt) {
+			next = job
+		}
+	}
+	if next == nil {
+		return nil, nil
+	}
+
+	next.Status = StatusRunning
+	next.Attempts++
+	next.UpdatedAt = now.UTC()
+	claimed := *next
+	return &claimed, nil
+}
+
+// Complete removes a finished job
+func (q *MemoryQueue) Complete(ctx context.Context, job *Job) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	delete(q.jobs, job.ID)
+	return nil
+}
+
+// Retry queues a failed job again
+func (q *MemoryQueue) Retry(ctx context.Context, job *Job, runAt time.Time, cause error) error {
+	return q.update(job.ID, func(stored *Job) {
+		stored.Status = StatusQueued
+		stored.RunAt = runAt.UTC()
+		stored.LastError = cause.Error()
+	})
+}
+
+// Bury moves a failed job to the dead letters
+func (q *MemoryQueue) Bury(ctx context.Context, job *Job, cause error) error {
+	return q.update(job.ID, func(stored *Job) {
+		stored.Status = StatusDead
+		stored.LastError = cause.Error()
+	})
+}
+
+// Cancel removes the queued jobs with a key
+func (q *MemoryQueue) Cancel(ctx context.Context, key string) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	for id, job := range q.jobs {
+		if job.Key == key && job.Status == StatusQueued {
+			delete(q.jobs, id)
+		}
+	}
+	return nil
+}
+
+// List returns the jobs in a status, the oldest first
+func (q *MemoryQueue) List(ctx context.Context, status Status, limit int) ([]Job, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	var jobs []Job
+	for _, job := range q.jobs {
+		if job.Status == status {
+			jobs = append(jobs, *job)
+		}
+	}
+	slices.SortFunc(jobs, func(a, b Job) int { return a.CreatedAt.Compare(b.CreatedAt) })
+
+	if len(jobs) > limit {
+		jobs = jobs[:limit]
+	}
+	return jobs, nil
+}
+
+// Requeue queues a dead job again with fresh attempts
+func (q *MemoryQueue) Requeue(ctx context.Context, id string) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	job, ok := q.jobs[id]
+	if !ok || job.Status != StatusDead {
+		return fmt.Errorf("dead job %s: %w", id, ErrNotFound)
+	}
+	job.Status = StatusQueued
+	job.Attempts = 0
+	job.RunAt = time.Now().UTC()
+	job.UpdatedAt = job.RunAt
+	return nil
+}
+
+// Recover has nothing to do, the jobs of a previous process are gone
+func (q *MemoryQueue) Recover(ctx context.Context) (int, error) {
+	return 0, nil
+}
+
+// update changes a stored job
+func (q *MemoryQueue) update(id string, change func(*Job)) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	job, ok := q.jobs[id]
+	if !ok {
+		return fmt.Errorf("job %s: %w", id, ErrNotFound)
+	}
+	change(job)
+	job.UpdatedAt = time.Now().UTC()
+	return nil
+}
@@ -0,0 +1,217 @@
+package jobs
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"math/rand/v2"
+	"sync"
+	"time"
+
+	"cyclone/internal/config"
+)
+
+// Handler runs a job. Returned errors are retried with backoff unless marked Permanent.
+type Handler func(ctx context.Context, job *Job) error
+
+// Pool runs the jobs of a queue on a bounded number of workers, limiting how many jobs of
+// a single installation run at once so one busy organization can't take all workers
+type Pool struct {
+	queue           Queue
+	workers         int
+	perInstallation int
+	maxAttempts     int
+	handlers        map[string]Handler
+
+	mu      sync.Mutex
+	running map[int64]int // running jobs per installation
+	wake    chan struct{}
+}
+
+// NewPool creates a pool running the jobs of queue, at most workers at once and at most
+// perInstallation of a single installation. Jobs are attempted maxAttempts times.
+func NewPool(queue Queue, workers, perInstallation, maxAttempts int) *Pool {
+	return &Pool{
+		queue:           queue,
+		workers:         workers,
+		perInstallation: perInstallation,
+		maxAttempts:     maxAttempts,
+		handlers:        make(map[string]Handler),
+		running:         make(map[int64]int),
+		wake:            make(chan struct{}, 1),
+	}
+}
+
+// Queue returns the queue of the pool
+func (p *Pool) Queue() Queue {
+	return p.queue
+}
+
+// Handle registers the handler of a kind of job. Handlers must be registered before Run.
+func (p *Pool) Handle(kind string, handler Handler) {
+	p.handlers[kind] = handler
+}
+
+// Enqueue adds a job to the queue, to run once it is due
+func (p *Pool) Enqueue(ctx context.Context, job *Job) error {
+	if job.MaxAttempts == 0 {
+		job.MaxAttempts = p.maxAttempts
+	}
+	if err := p.queue.Enqueue(ctx, job); err != nil {
+		return err
+	}
+	p.Notify()
+	return nil
+}
+
+// Run runs jobs until ctx is done, then waits for the running ones, which are canceled
+// and queued again. Jobs interrupted by a previous process are run again first.
+func (p *Pool) Run(ctx context.Context) {
+	if recovered, err := p.queue.Recover(ctx); err != nil {
+		log.Printf("Error recovering interrupted jobs: %v", err)
+	} else if recovered > 0 {
+		log.Printf("Recovered %d interrupted job(s)", recovered)
+	}
+
+	slots := make(chan struct{}, p.workers)
+	ticker := time.NewTicker(config.JOB_POLL_INTERVAL)
+	defer ticker.Stop()
+
+	var wg sync.WaitGroup
+	for {
+		// Start due jobs as long as workers are free
+		for p.dispatch(ctx, slots, &wg) {
+		}
+
+		select {
+		case <-ctx.Done():
+			wg.Wait()
+			return
+		case <-p.wake:
+		case <-ticker.C:
+		}
+	}
+}
+
+// dispatch claims a due job and starts it if a worker is free, reporting whether it did
+func (p *Pool) dispatch(ctx context.Context, slots chan struct{}, wg *sync.WaitGroup) bool {
+	select {
+	case slots <- struct{}{}:
+	default:
+		return false
+	}
+
+	job, err := p.queue.Claim(ctx, p.busyInstallations())
+	if err != nil || job == nil {
+		if err != nil && ctx.Err() == nil {
+			log.Printf("Error claiming job: %v", err)
+		}
+		<-slots
+		return false
+	}
+
+	p.mu.Lock()
+	p.running[job.InstallationID]++
+	p.mu.Unlock()
+
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		defer func() {
+			p.mu.Lock()
+			if p.running[job.InstallationID]--; p.running[job.InstallationID] == 0 {
+				delete(p.running, job.InstallationID)
+			}
+			p.mu.Unlock()
+			<-slots
+			p.Notify()
+		}()
+		p.run(ctx, job)
+	}()
+	return true
+}
+
+// busyInstallations returns the installations running as many jobs as they may
+func (p *Pool) busyInstallations() []int64 {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	var busy []int64
+	for installationID, running := range p.running {
+		if running >= p.perInstallation {
+			busy = append(busy, installationID)
+		}
+	}
+	return busy
+}
+
+// run runs a claimed job and records the outcome: done, retried later, or dead
+func (p *Pool) run(ctx context.Context, job *Job) {
+	log.Printf("Running %s job %s (attempt %d of %d)", job.Kind, job.ID, job.Attempts, job.MaxAttempts)
+
+	err := p.call(ctx, job)
+	if ctx.Err() != nil {
+		// Shutting down, queue the job again so the next start or another process runs it
+		log.Printf("Interrupted %s job %s: %v", job.Kind, job.ID, err)
+		if err := p.queue.Retry(context.WithoutCancel(ctx), job, time.Now(), fmt.Errorf("interrupted by shutdown: %w", ctx.Err())); err != nil {
+			log.Printf("Error requeueing interrupted job %s, it is recovered on the next start: %v", job.ID, err)
+		}
+		return
+	}
+
+	switch {
+	case err == nil:
+		if err := p.queue.Complete(ctx, job); err != nil {
+			log.Printf("Error completing job %s: %v", job.ID, err)
+		}
+
+	case IsPermanent(err) || job.Attempts >= job.MaxAttempts:
+		log.Printf("Job %s (%s) failed for good after %d attempt(s): %v", job.ID, job.Kind, job.Attempts, err)
+		if err := p.queue.Bury(ctx, job, err); err != nil {
+			log.Printf("Error moving job %s to the dead letters: %v", job.ID, err)
+		}
+
+	default:
+		delay := retryDelay(job.Attempts)
+		log.Printf("Job %s (%s) failed, retrying in %s: %v", job.ID, job.Kind, delay.Round(time.Second), err)
+		if err := p.queue.Retry(ctx, job, time.Now().Add(delay), err); err != nil {
+			log.Printf("Error retrying job %s: %v", job.ID, err)
+		}
+	}
+}
+
+// call runs the handler of a job with a timeout, turning a panic into a permanent error
+func (p *Pool) call(ctx context.Context, job *Job) (err error) {
+	handler, ok := p.handlers[job.Kind]
+	if !ok {
+		return Permanent(fmt.Errorf("no handler for %s jobs", job.Kind))
+	}
+
+	defer func() {
+		if r := recover(); r != nil {
+			err = Permanent(fmt.Errorf("panic: %v", r))
+		}
+	}()
+
+	ctx, cancel := context.WithTimeout(ctx, config.JOB_TIMEOUT)
+	defer cancel()
+	return handler(ctx, job)
+}
+
+// Notify wakes Run up to claim jobs, e.g. after they were requeued
+func (p *Pool) Notify() {
+	select {
+	case p.wake <- struct{}{}:
+	default:
+	}
+}
+
+// retryDelay returns the wait before the next attempt of a job: full jitter over an
+// exponentially growing window
+func retryDelay(attempt int) time.Duration {
+	window := config.JOB_RETRY_MAX_DELAY
+	if attempt < 16 {
+		window = min(config.JOB_RETRY_BASE_DELAY<<(attempt-1), config.JOB_RETRY_MAX_DELAY)
+	}
+	return window/2 + time.Duration(rand.Int64N(int64(window/2)+1))
+}
@@ -0,0 +1,158 @@
+package jobs
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"cyclone/internal/config"
+)
+
+// jobTable is the Supabase table jobs are kept in, see the README for its schema
+const jobTable = "job"
+
+// enqueueFunction is the database function replacing or inserting keyed jobs in one
+// statement, see the README
+const enqueueFunction = "enqueue_job"
+
+// claimCandidates is how many due jobs Claim tries, in case other claims come first
+const claimCandidates = 10
+
+// SupabaseQueue keeps jobs in a Supabase table, so they survive restarts
+type SupabaseQueue struct {
+	client config.DatabaseClient
+}
+
+// NewSupabaseQueue creates a queue in the Supabase database
+func NewSupabaseQueue(cfg *config.Config) *SupabaseQueue {
+	return &SupabaseQueue{client: config.NewSupabaseClient(cfg.SupabaseURL, cfg.SupabaseAPIKey)}
+}
+
+// Enqueue adds a job, see Queue. Keyed jobs are upserted by a database function, so
+// concurrent deliveries for the same key can't both insert a job.
+func (q *SupabaseQueue) Enqueue(ctx context.Context, job *Job) error {
+	if job.Key != "" {
+		var stored Job
+		if err := q.client.RPC(ctx, enqueueFunction, map[string]any{"new_job": job}, &stored); err != nil {
+			return fmt.Errorf("failed to enqueue %s job: %w", job.Kind, err)
+		}
+		job.ID = stored.ID
+		return nil
+	}
+
+	if err := q.client.Insert(ctx, jobTable, job, nil); err != nil {
+		return fmt.Errorf("failed to enqueue %s job: %w", job.Kind, err)
+	}
+	return nil
+}
+
+// Claim marks the next due job as running, see Queue. Claiming only succeeds if the job
+// is still queued with the attempts read, so no two workers run the same job.
+func (q *SupabaseQueue) Claim(ctx context.Context, busy []int64) (*Job, error) {
+	query := fmt.Sprintf("status=eq.%s&run_at=lte.%s&order=run_at.asc&limit=%d", StatusQueued, timestamp(time.Now()), claimCandidates)
+	if len(busy) > 0 {
+		ids := make([]string, 0, len(busy))
+		for _, id := range busy {
+			ids = append(ids, strconv.FormatInt(id, 10))
+		}
+		query += "&installation_id=not.in.(" + strings.Join(ids, ",") + ")"
+	}
+
+	var due []Job
+	if err := q.client.Select(ctx, jobTable, query, &due); err != nil {
+		return nil, fmt.Errorf("failed to look up due jobs: %w", err)
+	}
+
+	for _, job := range due {
+		var claimed []Job
+		query := fmt.Sprintf("id=eq.%s&status=eq.%s&attempts=eq.%d", url.QueryEscape(job.ID), StatusQueued, job.Attempts)
+		values := map[string]any{"status": StatusRunning, "attempts": job.Attempts + 1, "updated_at": time.Now().UTC()}
+		if err := q.client.UpdateReturning(ctx, jobTable, query, values, &claimed); err != nil {
+			return nil, fmt.Errorf("failed to claim job %s: %w", job.ID, err)
+		}
+		if len(claimed) > 0 {
+			return &claimed[0], nil
+		}
+	}
+
+	return nil, nil
+}
+
+// Complete removes a finished job
+func (q *SupabaseQueue) Complete(ctx context.Context, job *Job) error {
+	if err := q.client.Delete(ctx, jobTable, "id=eq."+url.QueryEscape(job.ID)); err != nil {
+		return fmt.Errorf("failed to complete job %s: %w", job.ID, err)
+	}
+	return nil
+}
+
+// Retry queues a failed job again
+func (q *SupabaseQueue) Retry(ctx context.Context, job *Job, runAt time.Time, cause error) error {
+	values := map[string]any{"status": StatusQueued, "run_at": runAt.UTC(), "last_error": cause.Error(), "updated_at": time.Now().UTC()}
+	if err := q.client.Update(ctx, jobTable, "id=eq."+url.QueryEscape(job.ID), values); err != nil {
+		return fmt.Errorf("failed to retry job %s: %w", job.ID, err)
+	}
+	return nil
+}
+
+// Bury moves a failed job to the dead letters
+func (q *SupabaseQueue) Bury(ctx context.Context, job *Job, cause error) error {
+	values := map[string]any{"status": StatusDead, "last_error": cause.Error(), "updated_at": time.Now().UTC()}
+	if err := q.client.Update(ctx, jobTable, "id=eq."+url.QueryEscape(job.ID), values); err != nil {
+		return fmt.Errorf("failed to bury job %s: %w", job.ID, err)
+	}
+	return nil
+}
+
+// Cancel removes the queued jobs with a key
+func (q *SupabaseQueue) Cancel(ctx context.Context, key string) error {
+	query := fmt.Sprintf("key=eq.%s&status=eq.%s", url.QueryEscape(key), StatusQueued)
+	if err := q.client.Delete(ctx, jobTable, query); err != nil {
+		return fmt.Errorf("failed to cancel jobs %s: %w", key, err)
+	}
+	return nil
+}
+
+// List returns the jobs in a status, the oldest first
+func (q *SupabaseQueue) List(ctx context.Context, status Status, limit int) ([]Job, error) {
+	var jobs []Job
+	query := fmt.Sprintf("status=eq.%s&order=created_at.asc&limit=%d", url.QueryEscape(string(status)), limit)
+	if err := q.client.Select(ctx, jobTable, query, &jobs); err != nil {
+		return nil, fmt.Errorf("failed to list %s jobs: %w", status, err)
+	}
+	return jobs, nil
+}
+
+// Requeue queues a dead job again with fresh attempts
+func (q *SupabaseQueue) Requeue(ctx context.Context, id string) error {
+	var requeued []Job
+	query := fmt.Sprintf("id=eq.%s&status=eq.%s", url.QueryEscape(id), StatusDead)
+	now := time.Now().UTC()
+	values := map[string]any{"status": StatusQueued, "attempts": 0, "run_at": now, "updated_at": now}
+	if err := q.client.UpdateReturning(ctx, jobTable, query, values, &requeued); err != nil {
+		return fmt.Errorf("failed to requeue job %s: %w", id, err)
+	}
+	if len(requeued) == 0 {
+		return fmt.Errorf("dead job %s: %w", id, ErrNotFound)
+	}
+	return nil
+}
+
+// Recover queues the jobs left running by a previous process again
+func (q *SupabaseQueue) Recover(ctx context.Context) (int, error) {
+	var recovered []Job
+	now := time.Now().UTC()
+	values := map[string]any{"status": StatusQueued, "run_at": now, "updated_at": now}
+	if err := q.client.UpdateReturning(ctx, jobTable, "status=eq."+string(StatusRunning), values, &recovered); err != nil {
+		return 0, fmt.Errorf("failed to recover interrupted jobs: %w", err)
+	}
+	return len(recovered), nil
+}
+
+// timestamp formats a time for a PostgREST filter
+func timestamp(t time.Time) string {
+	return url.QueryEscape(t.UTC().Format(time.RFC3339Nano))
+}
@@ -1,63 +1,84 @@
 package review
 
 import (
-	"bytes"
-	"encoding/json"
+	"context"
+	"errors"
 	"fmt"
 	"log"
-	"net/http"
-	"time"
 
 	"cyclone/internal/config"
 )
 
-// AIClient handles all AI/Claude API operations
-type AIClient struct {
-	apiKey string
-	model  string
+// GenerateReview generates an AI review with the repository's configured provider and model.
+// The review is requested as a structured tool call; the legacy text protocol is only
+// used when the structured call fails. The error wraps ErrReviewUnavailable when no model replied.
+func (ai *AIClient) GenerateReview(ctx context.Context, diff *PRDiff, title, body string, repoConfig *config.RepositoryConfig) (ReviewResult, error) {
+	return ai.generateReview(ctx, diff.Text, diff.ContextText(), title, body, "", repoConfig)
 }
 
-// ClaudeResponse represents the response from Claude API
-type ClaudeResponse struct {
-	Content []struct {
-		Text string `json:"text"`
-	} `json:"content"`
-}
+// generateReview reviews a diff; codeContext is the surrounding code of the changes and
+// scope optionally tells Claude which part of the PR it sees
+func (ai *AIClient) generateReview(ctx context.Context, diff, codeContext, title, body, scope string, repoConfig *config.RepositoryConfig) (ReviewResult, error) {
+	result, err := ai.generateStructuredReview(ctx, diff, codeContext, title, body, scope, repoConfig)
+	if err == nil {
+		return result, nil
+	}
+	if errors.Is(err, ErrReviewUnavailable) || ctx.Err() != nil {
+		// The text format would hit the same failing provider
+		return ReviewResult{}, err
+	}
+	log.Printf("Structured review failed, falling back to text format: %v", err)
 
-// ClaudeRequest represents a request to Claude API
-type ClaudeRequest struct {
-	Model     string `json:"model"`
-	MaxTokens int    `json:"max_tokens"`
-	Messages  []struct {
-		Role    string `json:"role"`
-		Content string `json:"content"`
-	} `json:"messages"`
+	claudeReview, err := ai.callClaudeAPI(ctx, diff, codeContext, title, body, scope, repoConfig)
+	if err != nil {
+		return ReviewResult{}, err
+	}
+	return ai.parseClaudeResponse(claudeReview, diff), nil
 }
 
-// NewAIClient creates a new AI client with the provided API key and model
-func NewAIClient(apiKey, model string) *AIClient {
-	return &AIClient{
-		apiKey: apiKey,
-		model:  model,
+// generateStructuredReview asks the model to submit the review through the submit_review tool
+func (ai *AIClient) generateStructuredReview(ctx context.Context, diff, codeContext, title, body, scope string, repoConfig *config.RepositoryConfig) (ReviewResult, error) {
+	prompt := buildReviewPrompt(diff, codeContext, title, body, scope, repoConfig, structuredResponseFormat)
+
+	completion, err := ai.complete(ctx, repoConfig, CompletionRequest{
+		MaxTokens: 8000,
+		Messages:  []Message{{Role: "user", Content: prompt}},
+		Tool:      &submitReviewTool,
+	})
+	if err != nil {
+		return ReviewResult{}, err
+	}
+
+	review, err := parseStructuredReview(completion.ToolInput)
+	if err != nil {
+		return ReviewResult{}, err
 	}
+	return review.toResult(), nil
 }
 
-// GenerateReview generates an AI review using Claude with repository-specific configuration
-func (ai *AIClient) GenerateReview(diff, title, body string, repoConfig *config.RepositoryConfig) ReviewResult {
-	claudeReview := ai.callClaudeAPI(diff, title, body, repoConfig)
-	return ai.parseClaudeResponse(claudeReview, diff)
+// callClaudeAPI requests a review in the legacy $$-delimited text format
+func (ai *AIClient) callClaudeAPI(ctx context.Context, diff, codeContext, title, body, scope string, repoConfig *config.RepositoryConfig) (string, error) {
+	prompt := buildReviewPrompt(diff, codeContext, title, body, scope, repoConfig, legacyResponseFormat)
+	return ai.sendMessages(ctx, repoConfig, []Message{{Role: "user", Content: prompt}}, 8000)
 }
 
-// callClaudeAPI makes a request to Claude API with repository-specific configuration
-func (ai *AIClient) callClaudeAPI(diff, title, body string, repoConfig *config.RepositoryConfig) string {
-	prompt := fmt.Sprintf(`You are Cyclone, an AI code review assistant. Please review this GitHub pull request and provide constructive feedback.
+// buildReviewPrompt assembles the review prompt with the given response format instructions
+func buildReviewPrompt(diff, codeContext, title, body, scope string, repoConfig *config.RepositoryConfig, responseFormat string) string {
+	if scope != "" {
+		scope = "\n**Review Scope:** " + scope + "\n"
+	}
+	if codeContext != "" {
+		scope += "\n**Surrounding Code** (the changed files at the head commit with line numbers, for reference only - comment on the changes below, not on this code):\n" + codeContext
+	}
+
+	return fmt.Sprintf(`You are Cyclone, an AI code review assistant. Please review this GitHub pull request and provide constructive feedback.
 
 **PR Title:** %s
 
 **PR Description:** %s
 
 **Review Precision**: %s
- 
+%s
 **Code Changes:**
 %s
 
@@ -89,7 +110,15 @@ Please provide:
 - 🧪 **test**: Testing coverage or quality
 - 🔧 **refactor**: Code organization improvements
 
-**Response Structure:**
+%s
+
+%s
+
+Be constructive, helpful, and focus on actionable feedback.`, title, body, config.GetPrecisionGuidelines(repoConfig.Precision), scope, diff, responseFormat, repoConfig.CustomPrompt)
+}
+
+// legacyResponseFormat asks for the $$-delimited text protocol parsed by parseClaudeResponse
+const legacyResponseFormat = `**Response Structure:**
 Please structure your response EXACTLY as follows:
 
 SUMMARY: $$
@@ -117,71 +146,111 @@ Examples:
 PR_COMMENT:main.go:45: 🔍 **nit**: Consider using a more descriptive variable name like 'userCount' instead of 'cnt'
 PR_COMMENT:utils.js:123: ⚠️ **issue**: This function needs error handling for the API call
 PR_COMMENT:api/handler.py:67: 🚫 **blocking**: 🔒 **security**: Potential SQL injection vulnerability - use parameterized queries
+PR_COMMENT:service.go:45-52: 💡 **suggestion**: This whole block could be replaced by a single call to strings.Join
+PR_COMMENT:config.go:-30: ❓ **question**: Was removing this default intentional?
 
 
 **IMPORTANT Rules:**
-- Use SINGLE line numbers only, NOT ranges like "75-82"
+- Use "75-82" to comment on a range of lines within the same diff hunk
+- Prefix the line number with a minus, e.g. "-30" or "-30-34", to comment on lines deleted by the PR
 - Always include the colon after **[category]**:
 - Always use the $$ delimiters for all sections
 - Keep general analysis in SUMMARY, use PR_COMMENT only for specific line feedback
-- Include code examples in PR_COMMENT when suggesting alternatives
+- Include code examples in PR_COMMENT when suggesting alternatives`
+
+// structuredResponseFormat asks for the review as a submit_review tool call
+const structuredResponseFormat = `**Response Structure:**
+Submit your review by calling the submit_review tool exactly once:
+- summary: **A warm, engaging summary** with emojis and thoughtful analysis (not just bullet points) including:
+  - Brief overall analysis of what this PR accomplishes
+  - Key changes made
+  - Impact assessment (what this means for the codebase)
+  - Good patterns you noticed (acknowledge positive aspects)
+  - Any overarching concerns or recommendations
+  - Use emojis carefully to make it visually appealing (🚀 ✨ 🎯 📈 🔧 etc.)
+- poem: A short, lighthearted poem (2-4 lines) inspired by the changes made formatted in italic
+- comments: line-specific feedback, each with the file path, the line number in the new version of the file, a severity, an optional focus area and the comment body (markdown, may include code examples). Set start_line to comment on a range of lines within the same diff hunk, and side (and start_side) to LEFT to comment on lines deleted by the PR, using their line number in the old version of the file
+
+**IMPORTANT Rules:**
+- Only comment on lines that are part of the diff
+- Do not repeat the severity or focus area in the comment body, they are rendered automatically
+- Keep general analysis in the summary, use comments only for specific line feedback
+- Include code examples in comments when suggesting alternatives
+- For small, self-contained fixes add a suggestion: original is the exact current content of lines start_line..line (or just line), replacement is the code replacing them. Developers can commit it with one click, so it must be complete and correctly indented`
 
+// ExplainDiff answers a developer's question about a pull request diff
+func (ai *AIClient) ExplainDiff(ctx context.Context, diff, title, body, question string, repoConfig *config.RepositoryConfig) (string, error) {
+	prompt := fmt.Sprintf(`You are Cyclone, an AI code review assistant. A developer asked you a question about this GitHub pull request.
+
+**PR Title:** %s
+
+**PR Description:** %s
+
+**Code Changes:**
 %s
 
-Be constructive, helpful, and focus on actionable feedback.`, title, body, config.GetPrecisionGuidelines(repoConfig.Precision), diff, repoConfig.CustomPrompt)
+**Question:** %s
 
-	reqBody := ClaudeRequest{
-		Model:     ai.model, // configurable: claude-sonnet-4-20250514, claude-3-5-sonnet-20241022, claude-3-haiku-20240307
-		MaxTokens: 8000,
-		Messages: []struct {
-			Role    string `json:"role"`
-			Content string `json:"content"`
-		}{
-			{
-				Role:    "user",
-				Content: prompt,
-			},
-		},
-	}
+Answer the question directly and concisely in GitHub markdown, referring to specific files and lines where helpful.
+Use collaborative language and include short code examples only when they clarify the answer.
 
-	jsonData, err := json.Marshal(reqBody)
-	if err != nil {
-		log.Printf("Error marshaling request: %v", err)
-		return "Error generating AI review"
-	}
+%s`, title, body, diff, question, repoConfig.CustomPrompt)
 
-	req, err := http.NewRequest("POST", "https://api.anthropic.com/v1/messages", bytes.NewBuffer(jsonData))
-	if err != nil {
-		log.Printf("Error creating request: %v", err)
-		return "Error generating AI review"
-	}
+	return ai.sendMessages(ctx, repoConfig, []Message{{Role: "user", Content: prompt}}, 2000)
+}
 
-	req.Header.Set("Content-Type", "application/json")
-	req.Header.Set("x-api-key", ai.apiKey)
-	req.Header.Set("anthropic-version", "2023-06-01")
+// ReplyInThread continues a conversation in one of Cyclone's review threads. The
+// thread starts with Cyclone's original comment and ends with the developer's reply.
+func (ai *AIClient) ReplyInThread(ctx context.Context, title, path, diffHunk string, thread []ThreadComment, repoConfig *config.RepositoryConfig) (string, error) {
+	prompt := fmt.Sprintf(`You are Cyclone, an AI code review assistant. You left a review comment on a GitHub pull request and a developer replied to it.
 
-	client := &http.Client{Timeout: 60 * time.Second}
-	resp, err := client.Do(req)
-	if err != nil {
-		log.Printf("Error calling Claude API: %v", err)
-		return "Error generating AI review"
-	}
-	defer resp.Body.Close()
+**PR Title:** %s
+
+**File:** %s
 
-	if resp.StatusCode != http.StatusOK {
-		log.Printf("Claude API returned status %d", resp.StatusCode)
-		return "Error generating AI review"
+**Code under discussion:**
+%s
+
+Continue the conversation as Cyclone:
+- Answer questions about your comment and explain the "why" behind it
+- If the developer explains the code is intentional and their reasoning is sound, acknowledge it and withdraw the concern
+- If the concern still stands, say so politely and explain what would address it
+- Keep it short, use collaborative language and GitHub markdown
+
+%s`, title, path, diffHunk, repoConfig.CustomPrompt)
+
+	messages := []Message{{Role: "user", Content: prompt}}
+	for _, c := range thread {
+		role, content := "user", fmt.Sprintf("**@%s** wrote:\n%s", c.Author, c.Body)
+		if c.FromCyclone {
+			role, content = "assistant", c.Body
+		}
+
+		// Models expect alternating roles, so merge consecutive comments from the same side
+		last := &messages[len(messages)-1]
+		if last.Role == role {
+			last.Content += "\n\n" + content
+			continue
+		}
+		messages = append(messages, Message{Role: role, Content: content})
 	}
 
-	var claudeResp ClaudeResponse
-	if err := json.NewDecoder(resp.Body).Decode(&claudeResp); err != nil {
-		log.Printf("Error decoding response: %v", err)
-		return "Error generating AI review"
+	return ai.sendMessages(ctx, repoConfig, messages, 2000)
+}
+
+// sendMessages sends a conversation to the repository's model and returns the text of the reply
+func (ai *AIClient) sendMessages(ctx context.Context, repoConfig *config.RepositoryConfig, messages []Message, maxTokens int) (string, error) {
+	completion, err := ai.complete(ctx, repoConfig, CompletionRequest{
+		MaxTokens: maxTokens,
+		Messages:  messages,
+	})
+	if err != nil {
+		return "", err
 	}
 
-	if len(claudeResp.Content) > 0 {
-		return claudeResp.Content[0].Text
+	if completion.Text == "" {
+		return "", fmt.Errorf("no response from AI provider")
 	}
 
-	return "No response from Claude"
+	return completion.Text, nil
 }
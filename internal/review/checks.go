@@ -0,0 +1,130 @@
+package review
+
+import (
+	"fmt"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/google/go-github/v57/github"
+
+	"cyclone/internal/config"
+)
+
+// CheckRunName is the name of Cyclone's check run, as shown on the PR and in branch protection
+const CheckRunName = "Cyclone AI Code Review"
+
+// Check run conclusions used by Cyclone
+const (
+	ConclusionSuccess = "success"
+	ConclusionFailure = "failure"
+	ConclusionNeutral = "neutral"
+)
+
+// maxAnnotationsPerRequest is GitHub's limit of annotations per check run update
+const maxAnnotationsPerRequest = 50
+
+// maxCheckSummaryLength stays below GitHub's 65535 character limit of check run summaries
+const maxCheckSummaryLength = 60000
+
+// annotationLevels maps comment severities to check run annotation levels
+var annotationLevels = map[Severity]string{
+	SeverityBlocking: "failure",
+	SeverityIssue:    "warning",
+}
+
+// CheckConclusion decides the conclusion of the check run for a review according to the
+// repository's check policy
+func CheckConclusion(result ReviewResult, policy config.CheckPolicy) string {
+	if policy == config.CheckPolicyNever {
+		return ConclusionNeutral
+	}
+
+	for _, comment := range result.Comments {
+		switch comment.Severity {
+		case SeverityBlocking:
+			return ConclusionFailure
+		case SeverityIssue:
+			if policy == config.CheckPolicyIssue {
+				return ConclusionFailure
+			}
+		}
+	}
+	return ConclusionSuccess
+}
+
+// CheckTitle summarizes the comments of a review by severity, e.g. "1 blocking, 2 issue, 3 other"
+func CheckTitle(result ReviewResult) string {
+	counts := make(map[Severity]int)
+	for _, comment := range result.Comments {
+		counts[comment.Severity]++
+	}
+
+	var parts []string
+	for _, severity := range []Severity{SeverityBlocking, SeverityIssue} {
+		if counts[severity] > 0 {
+			parts = append(parts, fmt.Sprintf("%d %s", counts[severity], severity))
+		}
+	}
+	if other := len(result.Comments) - counts[SeverityBlocking] - counts[SeverityIssue]; other > 0 {
+		parts = append(parts, fmt.Sprintf("%d other", other))
+	}
+
+	if len(parts) == 0 {
+		return "No comments"
+	}
+	return strings.Join(parts, ", ") + " comment(s)"
+}
+
+// CheckSummary shortens a review summary to fit into a check run, cutting it at a rune
+// boundary so the emoji of the summary stay valid UTF-8
+func CheckSummary(summary string) string {
+	if len(summary) <= maxCheckSummaryLength {
+		return summary
+	}
+	cut := maxCheckSummaryLength
+	for cut > 0 && !utf8.RuneStart(summary[cut]) {
+		cut--
+	}
+	return summary[:cut] + "\n\n*… see the review on the PR for the full summary*"
+}
+
+// CheckAnnotations turns the review comments into check run annotations. Comments on
+// removed lines are left out, as annotations can only point at the new version of a file.
+func CheckAnnotations(result ReviewResult) []*github.CheckRunAnnotation {
+	var annotations []*github.CheckRunAnnotation
+	for _, comment := range result.Comments {
+		if comment.Side == "LEFT" {
+			continue
+		}
+
+		level, ok := annotationLevels[comment.Severity]
+		if !ok {
+			level = "notice"
+		}
+		title := "Cyclone"
+		if comment.Severity != "" {
+			title = "Cyclone: " + string(comment.Severity)
+		}
+		if comment.FocusArea != "" {
+			title += " (" + string(comment.FocusArea) + ")"
+		}
+
+		annotations = append(annotations, &github.CheckRunAnnotation{
+			Path:            github.String(comment.Path),
+			StartLine:       github.Int(annotationStart(comment)),
+			EndLine:         github.Int(comment.Line),
+			AnnotationLevel: github.String(level),
+			Title:           github.String(title),
+			Message:         github.String(comment.Body),
+		})
+	}
+	return annotations
+}
+
+// annotationStart returns the first line of the new version of the file a comment covers
+func annotationStart(comment ReviewComment) int {
+	if comment.StartLine > 0 && (comment.StartSide == "" || comment.StartSide == "RIGHT") {
+		return comment.StartLine
+	}
+	return comment.Line
+}
@@ -0,0 +1,226 @@
+package review
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+	"sync"
+
+	"cyclone/internal/config"
+)
+
+// duplicateSimilarity is the word similarity above which two comments on nearby lines
+// are considered the same finding
+const duplicateSimilarity = 0.6
+
+// submitSummaryTool is the tool Claude calls to submit the merged summary of a chunked review
+var submitSummaryTool = Tool{
+	Name:        "submit_summary",
+	Description: "Submit the overall summary and poem for a pull request reviewed in parts.",
+	InputSchema: json.RawMessage(`{
+  "type": "object",
+  "properties": {
+    "summary": {"type": "string", "description": "Overall review summary in GitHub markdown"},
+    "poem": {"type": "string", "description": "A short, lighthearted poem (2-4 lines) in italic"}
+  },
+  "required": ["summary"],
+  "additionalProperties": false
+}`),
+}
+
+// EstimateTokens roughly estimates the number of tokens of a text (~4 characters per token)
+func EstimateTokens(text string) int {
+	return len(text) / 4
+}
+
+// SplitDiff groups the files of a diff into chunks of at most tokenBudget estimated tokens.
+// Files are kept whole and sorted by path, so files of the same package tend to end up
+// in the same chunk. A single file larger than the budget gets a chunk of its own.
+func SplitDiff(diff *PRDiff, tokenBudget int) []*PRDiff {
+	paths := make([]string, 0, len(diff.Files))
+	for path := range diff.Files {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+
+	var chunks []*PRDiff
+	var current []string
+	currentTokens := 0
+	for _, path := range paths {
+		tokens := EstimateTokens(diff.Files[path].Patch)
+		if len(current) > 0 && currentTokens+tokens > tokenBudget {
+			chunks = append(chunks, diff.subset(current))
+			current, currentTokens = nil, 0
+		}
+		current = append(current, path)
+		currentTokens += tokens
+	}
+	if len(current) > 0 {
+		chunks = append(chunks, diff.subset(current))
+	}
+
+	return chunks
+}
+
+// subset builds a diff containing only the given files
+func (d *PRDiff) subset(paths []string) *PRDiff {
+	sub := &PRDiff{Files: make(map[string]*FileDiff, len(paths)), Skipped: d.Skipped}
+
+	var sb strings.Builder
+	for _, path := range paths {
+		file := d.Files[path]
+		sub.Files[path] = file
+		sb.WriteString(fmt.Sprintf("=== %s ===\n", path))
+		sb.WriteString(file.Patch)
+		sb.WriteString("\n\n")
+	}
+	sub.Text = sb.String()
+
+	return sub
+}
+
+// GenerateChunkedReview reviews a large diff map-reduce style: the diff is split into
+// token-bounded chunks that are reviewed in parallel, then a synthesis call merges the
+// per-chunk summaries into one review and duplicate comments are dropped. Files of chunks
+// that could not be reviewed are added to the diff's skipped files; an error is only
+// returned when no chunk could be reviewed.
+func (ai *AIClient) GenerateChunkedReview(ctx context.Context, diff *PRDiff, title, body string, repoConfig *config.RepositoryConfig) (ReviewResult, error) {
+	chunks := SplitDiff(diff, config.CHUNK_TOKEN_BUDGET)
+	log.Printf("Reviewing large diff in %d chunks", len(chunks))
+
+	results := make([]ReviewResult, len(chunks))
+	errs := make([]error, len(chunks))
+	semaphore := make(chan struct{}, config.MAX_PARALLEL_CHUNKS)
+	var wg sync.WaitGroup
+
+	for i, chunk := range chunks {
+		wg.Add(1)
+		go func(i int, chunk *PRDiff) {
+			defer wg.Done()
+			semaphore <- struct{}{}
+			defer func() { <-semaphore }()
+
+			scope := fmt.Sprintf("This is part %d of %d of a large pull request. Only the files below are shown, the other parts are reviewed separately - don't flag code as missing just because it is not shown.", i+1, len(chunks))
+			results[i], errs[i] = ai.generateReview(ctx, chunk.Text, chunk.ContextText(), title, body, scope, repoConfig)
+		}(i, chunk)
+	}
+	wg.Wait()
+
+	var summaries []string
+	var comments []ReviewComment
+	var lastErr error
+	for i, result := range results {
+		if errs[i] != nil {
+			log.Printf("Review of part %d of %d failed: %v", i+1, len(chunks), errs[i])
+			lastErr = errs[i]
+			for path := range chunks[i].Files {
+				diff.Skipped = append(diff.Skipped, SkippedFile{Path: path, Reason: SkipReasonAIFailed})
+			}
+			continue
+		}
+		summaries = append(summaries, summaryBody(result.Summary))
+		comments = append(comments, result.Comments...)
+	}
+	if len(summaries) == 0 {
+		return ReviewResult{}, lastErr
+	}
+
+	summary, poem, err := ai.synthesizeSummaries(ctx, title, body, summaries, repoConfig)
+	if err != nil {
+		log.Printf("Summary synthesis failed, concatenating chunk summaries: %v", err)
+		summary, poem = joinSummaries(summaries), ""
+	}
+
+	notice := fmt.Sprintf("🧩 *This is a large PR, so it was reviewed in %d parts.*\n\n", len(chunks))
+
+	return ReviewResult{
+		Summary:  formatSummary(notice+summary, poem),
+		Comments: dedupeComments(comments),
+	}, nil
+}
+
+// synthesizeSummaries merges the summaries of the reviewed chunks into one summary and poem
+func (ai *AIClient) synthesizeSummaries(ctx context.Context, title, body string, summaries []string, repoConfig *config.RepositoryConfig) (string, string, error) {
+	prompt := fmt.Sprintf(`You are Cyclone, an AI code review assistant. A large GitHub pull request was reviewed in %d parts. Merge the reviews of the parts into one overall review.
+
+**PR Title:** %s
+
+**PR Description:** %s
+
+**Reviews of the parts:**
+%s
+
+Submit the result by calling the submit_summary tool:
+- summary: **A warm, engaging summary** with emojis and thoughtful analysis of the PR as a whole: what it accomplishes, the key changes, impact, good patterns and overarching concerns. Merge overlapping points and don't refer to the parts.
+- poem: A short, lighthearted poem (2-4 lines) inspired by the changes made formatted in italic
+
+%s`, len(summaries), title, body, joinSummaries(summaries), repoConfig.CustomPrompt)
+
+	completion, err := ai.complete(ctx, repoConfig, CompletionRequest{
+		MaxTokens: 4000,
+		Messages:  []Message{{Role: "user", Content: prompt}},
+		Tool:      &submitSummaryTool,
+	})
+	if err != nil {
+		return "", "", err
+	}
+
+	var merged struct {
+		Summary string `json:"summary"`
+		Poem    string `json:"poem"`
+	}
+	if err := json.Unmarshal(completion.ToolInput, &merged); err != nil {
+		return "", "", fmt.Errorf("invalid summary object: %w", err)
+	}
+	if strings.TrimSpace(merged.Summary) == "" {
+		return "", "", fmt.Errorf("invalid summary object: summary is required")
+	}
+
+	return strings.TrimSpace(merged.Summary), strings.TrimSpace(merged.Poem), nil
+}
+
+// summaryBody strips the Cyclone heading and the poem from a formatted review summary
+func summaryBody(summary string) string {
+	summary = strings.TrimPrefix(summary, ReviewHeader)
+	if i := strings.Index(summary, poemSeparator); i != -1 {
+		summary = summary[:i]
+	}
+	return strings.TrimSpace(summary)
+}
+
+// joinSummaries lists the summaries of the parts one after another
+func joinSummaries(summaries []string) string {
+	var parts []string
+	for i, s := range summaries {
+		parts = append(parts, fmt.Sprintf("#### Part %d\n\n%s", i+1, s))
+	}
+	return strings.Join(parts, "\n\n")
+}
+
+// dedupeComments drops comments that repeat an earlier comment on a nearby line of the same file
+func dedupeComments(comments []ReviewComment) []ReviewComment {
+	var unique []ReviewComment
+	for _, c := range comments {
+		duplicate := false
+		for _, u := range unique {
+			if c.Path == u.Path && abs(c.Line-u.Line) <= maxSnapDistance && textSimilarity(c.Body, u.Body) >= duplicateSimilarity {
+				duplicate = true
+				break
+			}
+		}
+		if !duplicate {
+			unique = append(unique, c)
+		}
+	}
+	return unique
+}
+
+func abs(n int) int {
+	if n < 0 {
+		return -n
+	}
+	return n
+}
@@ -0,0 +1,336 @@
+package review
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"path"
+	"regexp"
+	"sort"
+	"strings"
+
+	"cyclone/internal/config"
+)
+
+// contextWindow is how many lines around a change are included when no enclosing
+// function can be found
+const contextWindow = 20
+
+// maxEnclosingScan is how far above a change an enclosing function is looked for
+const maxEnclosingScan = 300
+
+// maxEnclosingLines is the longest enclosing function included, longer ones are
+// replaced by a window around the change
+const maxEnclosingLines = 150
+
+// HeadFiles reads files at the head commit of a PR through the contents API, caching
+// every file and directory it fetched
+type HeadFiles struct {
+	ctx          context.Context
+	githubClient *GitHubClient
+	owner        string
+	repo         string
+	ref          string
+
+	files map[string]string
+	dirs  map[string][]string
+}
+
+// NewHeadFiles creates a reader for the files of a repository at ref
+func NewHeadFiles(ctx context.Context, githubClient *GitHubClient, owner, repo, ref string) *HeadFiles {
+	return &HeadFiles{
+		ctx:          ctx,
+		githubClient: githubClient,
+		owner:        owner,
+		repo:         repo,
+		ref:          ref,
+		files:        make(map[string]string),
+		dirs:         make(map[string][]string),
+	}
+}
+
+// Content returns the content of a file
+func (h *HeadFiles) Content(path string) (string, error) {
+	if content, ok := h.files[path]; ok {
+		return content, nil
+	}
+
+	content, err := h.githubClient.GetFileContent(h.ctx, h.owner, h.repo, path, h.ref)
+	if err != nil {
+		return "", err
+	}
+	h.files[path] = content
+	return content, nil
+}
+
+// List returns the paths of the files in a directory
+func (h *HeadFiles) List(dir string) ([]string, error) {
+	if files, ok := h.dirs[dir]; ok {
+		return files, nil
+	}
+
+	files, err := h.githubClient.ListDirectory(h.ctx, h.owner, h.repo, dir, h.ref)
+	if err != nil {
+		return nil, err
+	}
+	h.dirs[dir] = files
+	return files, nil
+}
+
+// lineRange is an inclusive range of 1-based line numbers
+type lineRange struct {
+	start, end int
+}
+
+// contextBuilder collects the surrounding code of the changed files within a token budget
+type contextBuilder struct {
+	files    *HeadFiles
+	budget   int
+	included map[string][]lineRange // lines of each file already in the context
+}
+
+// AddContext attaches the surrounding code of every changed file to the diff, so the AI
+// sees helpers defined outside of the patch hunks. Small files are included whole; for
+// larger ones the functions enclosing the changes are included, and for Go the package
+// level definitions the changes refer to. Files that can't be read get no context.
+func AddContext(diff *PRDiff, files *HeadFiles) {
+	b := &contextBuilder{files: files, budget: config.CONTEXT_TOKEN_BUDGET, included: make(map[string][]lineRange)}
+
+	paths := make([]string, 0, len(diff.Files))
+	for p := range diff.Files {
+		paths = append(paths, p)
+	}
+	sort.Strings(paths)
+
+	// Enclosing code first, it matters most, then definitions with what's left. Once the
+	// budget is spent no more files are fetched.
+	for _, p := range paths {
+		if b.exhausted() {
+			return
+		}
+		b.addSurroundingCode(diff.Files[p])
+	}
+	for _, p := range paths {
+		if b.exhausted() {
+			return
+		}
+		if strings.HasSuffix(p, ".go") {
+			b.addGoDefinitions(diff.Files[p])
+		}
+	}
+}
+
+// ContextText renders the surrounding code of the files of the diff for the prompt
+func (d *PRDiff) ContextText() string {
+	paths := make([]string, 0, len(d.Files))
+	for p, f := range d.Files {
+		if f.Context != "" {
+			paths = append(paths, p)
+		}
+	}
+	sort.Strings(paths)
+
+	var sb strings.Builder
+	for _, p := range paths {
+		sb.WriteString(d.Files[p].Context)
+	}
+	return sb.String()
+}
+
+// exhausted reports whether the budget is spent
+func (b *contextBuilder) exhausted() bool {
+	return b.budget <= 0
+}
+
+// addSurroundingCode adds the whole file or the code enclosing its changes
+func (b *contextBuilder) addSurroundingCode(file *FileDiff) {
+	changed := changedLines(file)
+	if len(changed) == 0 {
+		return
+	}
+
+	content, err := b.files.Content(file.Path)
+	if err != nil {
+		log.Printf("No context for %s: %v", file.Path, err)
+		return
+	}
+	lines := splitLines(content)
+
+	if tokens := EstimateTokens(content); tokens <= config.MAX_FULL_FILE_TOKENS && tokens <= b.budget {
+		b.add(file, file.Path, fmt.Sprintf("=== %s (full file at head) ===\n", file.Path), lines, []lineRange{{1, len(lines)}})
+		return
+	}
+
+	var ranges []lineRange
+	for _, line := range changed {
+		ranges = append(ranges, enclosingRange(file.Path, content, lines, line))
+	}
+	b.add(file, file.Path, fmt.Sprintf("=== %s (code around the changes at head) ===\n", file.Path), lines, mergeRanges(ranges))
+}
+
+// add appends numbered lines of the file at source to the context of a changed file, as
+// far as the budget allows
+func (b *contextBuilder) add(file *FileDiff, source, heading string, lines []string, ranges []lineRange) {
+	var sb strings.Builder
+	for _, r := range ranges {
+		var block strings.Builder
+		for n := max(r.start, 1); n <= r.end && n <= len(lines); n++ {
+			block.WriteString(fmt.Sprintf("%5d | %s\n", n, lines[n-1]))
+		}
+
+		tokens := EstimateTokens(block.String())
+		if tokens > b.budget {
+			break
+		}
+		b.budget -= tokens
+		b.included[source] = append(b.included[source], r)
+		if sb.Len() > 0 {
+			sb.WriteString("  ... |\n")
+		}
+		sb.WriteString(block.String())
+	}
+
+	if sb.Len() > 0 {
+		file.Context += heading + sb.String() + "\n"
+	}
+}
+
+// changedLines returns the lines of the new version of a file touched by its hunks. For
+// hunks that only delete, the first line after the deletion is used.
+func changedLines(file *FileDiff) []int {
+	var changed []int
+	for _, hunk := range file.Hunks {
+		found := false
+		for _, dl := range hunk.Lines {
+			if dl.Kind == '+' {
+				changed = append(changed, dl.NewLine)
+				found = true
+			}
+		}
+		if !found && hunk.NewLines > 0 {
+			changed = append(changed, hunk.NewStart)
+		}
+	}
+	return changed
+}
+
+// enclosingRange returns the function or class around a line, detected by lightweight
+// parsing for Go, TypeScript/JavaScript and Python, or a window of lines around it
+func enclosingRange(filePath, content string, lines []string, line int) lineRange {
+	var r lineRange
+	var ok bool
+	switch path.Ext(filePath) {
+	case ".go":
+		r, ok = goEnclosingRange(filePath, content, line)
+	case ".ts", ".tsx", ".js", ".jsx", ".mjs", ".cjs":
+		r, ok = braceEnclosingRange(lines, line)
+	case ".py":
+		r, ok = pythonEnclosingRange(lines, line)
+	}
+
+	if !ok || r.end-r.start >= maxEnclosingLines {
+		// Unknown language or a huge function - a window around the change will do
+		return lineRange{max(line-contextWindow, 1), min(line+contextWindow, len(lines))}
+	}
+	return r
+}
+
+// tsFunctionRe matches lines starting a function, method or class in TypeScript/JavaScript
+var tsFunctionRe = regexp.MustCompile(`^\s*(export\s+)?(default\s+)?(async\s+)?(function\b|class\b|(public|private|protected|static|readonly|async|get|set|\s)*[A-Za-z_$][\w$]*\s*(<[^>]*>)?\([^;]*\)\s*(:\s*[^={;]+)?\{\s*$|(const|let|var)\s+[\w$]+\s*(:[^=]+)?=\s*(async\s*)?(function\b|(\([^)]*\)|[\w$]+)\s*(:\s*[^=]+)?=>))`)
+
+// tsControlRe matches control statements, which look like method definitions to tsFunctionRe
+var tsControlRe = regexp.MustCompile(`^\s*(\}\s*)?(if|for|while|switch|catch|else|do|with)\b`)
+
+// braceEnclosingRange finds the nearest function above a line whose braces enclose it
+func braceEnclosingRange(lines []string, line int) (lineRange, bool) {
+	for start := min(line, len(lines)); start >= 1 && line-start <= maxEnclosingScan; start-- {
+		if !tsFunctionRe.MatchString(lines[start-1]) || tsControlRe.MatchString(lines[start-1]) {
+			continue
+		}
+		if end, ok := matchingBrace(lines, start); ok && end >= line {
+			return lineRange{start, end}, true
+		}
+	}
+	return lineRange{}, false
+}
+
+// matchingBrace returns the line closing the first brace opened at or after start. Braces
+// in strings and comments are not accounted for, which is fine for context purposes.
+func matchingBrace(lines []string, start int) (int, bool) {
+	depth, opened := 0, false
+	for n := start; n <= len(lines); n++ {
+		for _, r := range lines[n-1] {
+			switch r {
+			case '{':
+				depth++
+				opened = true
+			case '}':
+				depth--
+			}
+		}
+		if opened && depth <= 0 {
+			return n, true
+		}
+	}
+	return 0, false
+}
+
+// pythonDefRe matches lines starting a function or class in Python
+var pythonDefRe = regexp.MustCompile(`^(\s*)(async\s+def|def|class)\s`)
+
+// pythonEnclosingRange finds the nearest def or class above a line that is indented less
+func pythonEnclosingRange(lines []string, line int) (lineRange, bool) {
+	if line < 1 || line > len(lines) {
+		return lineRange{}, false
+	}
+	indent := indentation(lines[line-1])
+
+	for start := line; start >= 1 && line-start <= maxEnclosingScan; start-- {
+		m := pythonDefRe.FindStringSubmatch(lines[start-1])
+		if m == nil || (start != line && len(m[1]) >= indent) {
+			continue
+		}
+
+		end := start
+		for n := start + 1; n <= len(lines); n++ {
+			if strings.TrimSpace(lines[n-1]) == "" {
+				continue
+			}
+			if indentation(lines[n-1]) <= len(m[1]) {
+				break
+			}
+			end = n
+		}
+		return lineRange{start, end}, true
+	}
+	return lineRange{}, false
+}
+
+func indentation(line string) int {
+	return len(line) - len(strings.TrimLeft(line, " \t"))
+}
+
+// mergeRanges sorts ranges and merges overlapping and adjacent ones
+func mergeRanges(ranges []lineRange) []lineRange {
+	sort.Slice(ranges, func(i, j int) bool { return ranges[i].start < ranges[j].start })
+
+	var merged []lineRange
+	for _, r := range ranges {
+		if n := len(merged); n > 0 && r.start <= merged[n-1].end+1 {
+			merged[n-1].end = max(merged[n-1].end, r.end)
+			continue
+		}
+		merged = append(merged, r)
+	}
+	return merged
+}
+
+// isIncluded reports whether lines of a file are already part of the context
+func (b *contextBuilder) isIncluded(source string, r lineRange) bool {
+	for _, inc := range b.included[source] {
+		if r.start >= inc.start && r.end <= inc.end {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,190 @@
+package review
+
+import (
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/scanner"
+	"go/token"
+	"go/types"
+	"log"
+	"path"
+	"sort"
+	"strings"
+
+	"cyclone/internal/config"
+)
+
+// goEnclosingRange returns the top-level declaration around a line of a Go file, with its
+// doc comment. Files with syntax errors are parsed as far as possible.
+func goEnclosingRange(filePath, content string, line int) (lineRange, bool) {
+	fset := token.NewFileSet()
+	f, _ := parser.ParseFile(fset, filePath, content, parser.ParseComments|parser.SkipObjectResolution)
+	if f == nil {
+		return lineRange{}, false
+	}
+
+	for _, decl := range f.Decls {
+		r := goDeclRange(fset, decl)
+		if r.start <= line && line <= r.end {
+			return r, true
+		}
+	}
+	return lineRange{}, false
+}
+
+// goDeclRange returns the lines of a declaration including its doc comment
+func goDeclRange(fset *token.FileSet, decl ast.Decl) lineRange {
+	start := decl.Pos()
+	switch d := decl.(type) {
+	case *ast.FuncDecl:
+		if d.Doc != nil {
+			start = d.Doc.Pos()
+		}
+	case *ast.GenDecl:
+		if d.Doc != nil {
+			start = d.Doc.Pos()
+		}
+	}
+	return lineRange{fset.Position(start).Line, fset.Position(decl.End()).Line}
+}
+
+// goDefinition is a package-level declaration of a Go package
+type goDefinition struct {
+	path  string
+	lines []string
+	r     lineRange
+}
+
+// addGoDefinitions adds the package-level definitions the added lines of a Go file refer
+// to, searched in the files of its package at head
+func (b *contextBuilder) addGoDefinitions(file *FileDiff) {
+	names := referencedIdentifiers(file.Patch)
+	if len(names) == 0 || b.exhausted() {
+		return
+	}
+
+	definitions, err := b.goPackageDefinitions(file.Path)
+	if err != nil {
+		log.Printf("No Go definitions for %s: %v", file.Path, err)
+		return
+	}
+
+	for _, name := range names {
+		def, ok := definitions[name]
+		if !ok || b.isIncluded(def.path, def.r) {
+			continue
+		}
+		b.add(file, def.path, fmt.Sprintf("=== %s: definition of %s (referenced by the changes) ===\n", def.path, name), def.lines, []lineRange{def.r})
+	}
+}
+
+// goPackageDefinitions maps the package-level names of the package of a Go file to their
+// declarations. Test files are only searched for changes to test files.
+func (b *contextBuilder) goPackageDefinitions(filePath string) (map[string]goDefinition, error) {
+	entries, err := b.files.List(path.Dir(filePath))
+	if err != nil {
+		return nil, err
+	}
+
+	isTest := strings.HasSuffix(filePath, "_test.go")
+	var sources []string
+	for _, entry := range entries {
+		if strings.HasSuffix(entry, ".go") && (isTest || !strings.HasSuffix(entry, "_test.go")) {
+			sources = append(sources, entry)
+		}
+	}
+	sort.Strings(sources)
+	if len(sources) > config.MAX_CONTEXT_PACKAGE_FILES {
+		sources = sources[:config.MAX_CONTEXT_PACKAGE_FILES]
+	}
+
+	packageName := ""
+	definitions := make(map[string]goDefinition)
+	for _, source := range append([]string{filePath}, sources...) {
+		content, err := b.files.Content(source)
+		if err != nil {
+			continue
+		}
+
+		fset := token.NewFileSet()
+		f, _ := parser.ParseFile(fset, source, content, parser.ParseComments|parser.SkipObjectResolution)
+		if f == nil || f.Name == nil {
+			continue
+		}
+		if packageName == "" {
+			packageName = f.Name.Name
+		} else if f.Name.Name != packageName {
+			// e.g. an external foo_test package in the same directory
+			continue
+		}
+
+		lines := splitLines(content)
+		for _, decl := range f.Decls {
+			for _, name := range goDeclNames(decl) {
+				if _, ok := definitions[name]; !ok {
+					definitions[name] = goDefinition{path: source, lines: lines, r: goDeclRange(fset, decl)}
+				}
+			}
+		}
+	}
+
+	return definitions, nil
+}
+
+// goDeclNames returns the names a top-level declaration defines. Methods are left out, as
+// the receiver type of a selector can't be told without type checking.
+func goDeclNames(decl ast.Decl) []string {
+	var names []string
+	switch d := decl.(type) {
+	case *ast.FuncDecl:
+		if d.Recv == nil {
+			names = append(names, d.Name.Name)
+		}
+	case *ast.GenDecl:
+		for _, spec := range d.Specs {
+			switch s := spec.(type) {
+			case *ast.TypeSpec:
+				names = append(names, s.Name.Name)
+			case *ast.ValueSpec:
+				for _, n := range s.Names {
+					names = append(names, n.Name)
+				}
+			}
+		}
+	}
+	return names
+}
+
+// referencedIdentifiers returns the identifiers used in the added lines of a Go patch,
+// leaving out selected fields and methods, builtins and the blank identifier
+func referencedIdentifiers(patch string) []string {
+	seen := make(map[string]bool)
+	var names []string
+
+	for _, line := range strings.Split(patch, "\n") {
+		if !strings.HasPrefix(line, "+") || strings.HasPrefix(line, "+++") {
+			continue
+		}
+		src := []byte(line[1:])
+
+		var s scanner.Scanner
+		fset := token.NewFileSet()
+		s.Init(fset.AddFile("", fset.Base(), len(src)), src, nil, 0)
+
+		previous := token.ILLEGAL
+		for {
+			_, tok, lit := s.Scan()
+			if tok == token.EOF {
+				break
+			}
+			if tok == token.IDENT && previous != token.PERIOD && lit != "_" && types.Universe.Lookup(lit) == nil && !seen[lit] {
+				seen[lit] = true
+				names = append(names, lit)
+			}
+			previous = tok
+		}
+	}
+
+	return names
+}
@@ -0,0 +1,94 @@
+package review
+
+import (
+	"fmt"
+	"log"
+	"regexp"
+	"strings"
+)
+
+// codeBlockPattern matches the fenced code blocks and the label of plain suggestions that
+// RenderSuggestions appends to a comment's body
+var codeBlockPattern = regexp.MustCompile("(?s)(\\*\\*Suggested change:\\*\\*\\s*)?```.*?```")
+
+// DedupeAgainstThreads drops findings Cyclone already raised in a thread of the PR, matched
+// by path, nearby line and body similarity. Findings repeating an open thread are returned
+// as repeated, as they still stand and should count towards the review event; findings
+// repeating a thread someone resolved are dropped for good.
+func DedupeAgainstThreads(result ReviewResult, diff *PRDiff, threads []ReviewThread, login string) (deduped ReviewResult, repeated []ReviewComment) {
+	var own []ReviewThread
+	for _, t := range threads {
+		if sameLogin(t.RootAuthor, login) {
+			own = append(own, t)
+		}
+	}
+	if len(own) == 0 {
+		return result, nil
+	}
+
+	var fresh []ReviewComment
+	resolved := 0
+	for _, c := range result.Comments {
+		thread := matchThread(c, diff, own)
+		switch {
+		case thread == nil || (thread.IsOutdated && !thread.IsResolved):
+			// New, or raised on code that has changed since and still applies
+			fresh = append(fresh, c)
+		case thread.IsResolved:
+			resolved++
+		default:
+			repeated = append(repeated, c)
+		}
+	}
+
+	if suppressed := len(repeated) + resolved; suppressed > 0 {
+		log.Printf("Suppressed %d finding(s) already raised on the PR (%d resolved)", suppressed, resolved)
+		result.Summary = appendSummarySection(result.Summary,
+			fmt.Sprintf("🔕 *%d finding(s) already raised in earlier Cyclone comments were not repeated.*", suppressed))
+	}
+
+	result.Comments = fresh
+	return result, repeated
+}
+
+// OutdatedThreads returns the open threads Cyclone started on code that has changed since,
+// whose root comment is not yet hidden
+func OutdatedThreads(threads []ReviewThread, login string) []ReviewThread {
+	var outdated []ReviewThread
+	for _, t := range threads {
+		if t.IsOutdated && !t.IsResolved && !t.RootIsMinimized && sameLogin(t.RootAuthor, login) {
+			outdated = append(outdated, t)
+		}
+	}
+	return outdated
+}
+
+// matchThread finds the thread a comment repeats
+func matchThread(c ReviewComment, diff *PRDiff, threads []ReviewThread) *ReviewThread {
+	path := c.Path
+	if file := diff.findFile(path); file != nil {
+		path = file.Path
+	}
+
+	for i, t := range threads {
+		line := t.Line
+		if line == 0 {
+			line = t.OriginalLine
+		}
+		if t.Path == path && abs(c.Line-line) <= maxSnapDistance && textSimilarity(c.Body, stripCodeBlocks(t.RootBody)) >= duplicateSimilarity {
+			return &threads[i]
+		}
+	}
+	return nil
+}
+
+// stripCodeBlocks removes the suggestions and code from a posted comment, leaving the
+// finding itself to compare with a new comment's body
+func stripCodeBlocks(body string) string {
+	return codeBlockPattern.ReplaceAllString(body, "")
+}
+
+// sameLogin compares GitHub logins, ignoring the "[bot]" suffix GraphQL leaves out for apps
+func sameLogin(a, b string) bool {
+	return a != "" && strings.EqualFold(strings.TrimSuffix(a, "[bot]"), strings.TrimSuffix(b, "[bot]"))
+}
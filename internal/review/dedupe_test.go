@@ -0,0 +1,57 @@
+package review
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestDedupeAgainstThreads(t *testing.T) {
+	body := "Check the error returned by Close, it is silently dropped."
+	suggested := plainSuggestion(ReviewComment{Body: body, Suggestion: &Suggestion{Replacement: "\tif err := f.Close(); err != nil {\n\t\treturn fmt.Errorf(\"failed to close file: %w\", err)\n\t}"}}).Body
+	committable := body + "\n\n```suggestion\n\tif err := f.Close(); err != nil {\n\t\treturn fmt.Errorf(\"failed to close file: %w\", err)\n\t}\n```"
+
+	tests := []struct {
+		name         string
+		thread       ReviewThread
+		wantFresh    int
+		wantRepeated int
+	}{
+		{"open thread", ReviewThread{Line: 12, RootBody: body}, 0, 1},
+		{"open thread with a suggestion", ReviewThread{Line: 12, RootBody: committable}, 0, 1},
+		{"open thread with a plain suggestion", ReviewThread{Line: 12, RootBody: suggested}, 0, 1},
+		{"resolved thread", ReviewThread{Line: 11, RootBody: committable, IsResolved: true}, 0, 0},
+		{"outdated thread", ReviewThread{OriginalLine: 12, RootBody: body, IsOutdated: true}, 1, 0},
+		{"other finding", ReviewThread{Line: 12, RootBody: "Rename this variable."}, 1, 0},
+		{"other line", ReviewThread{Line: 32, RootBody: body}, 1, 0},
+		{"other author", ReviewThread{Line: 12, RootBody: body, RootAuthor: "someone"}, 1, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			thread := tt.thread
+			thread.Path = "cmd/app/main.go"
+			if thread.RootAuthor == "" {
+				thread.RootAuthor = "cyclone-ai[bot]"
+			}
+			result := ReviewResult{
+				Summary:  formatSummary("Summary", "_poem_"),
+				Comments: []ReviewComment{{Path: "main.go", Line: 12, Body: body}},
+			}
+
+			deduped, repeated := DedupeAgainstThreads(result, newTestDiff(t), []ReviewThread{thread}, "cyclone-ai")
+			if len(deduped.Comments) != tt.wantFresh || len(repeated) != tt.wantRepeated {
+				t.Fatalf("got %d fresh and %d repeated comments, want %d and %d", len(deduped.Comments), len(repeated), tt.wantFresh, tt.wantRepeated)
+			}
+			if suppressed := tt.wantFresh == 0; suppressed != strings.Contains(deduped.Summary, "were not repeated") {
+				t.Errorf("summary doesn't match the suppressed findings:\n%s", deduped.Summary)
+			}
+		})
+	}
+}
+
+func TestStripCodeBlocks(t *testing.T) {
+	body := "Use a constant.\n\n**Suggested change:**\n```\nconst limit = 10\n```\n\nAnd another:\n\n```suggestion\nx := limit\n```"
+	if got, want := strings.Join(strings.Fields(stripCodeBlocks(body)), " "), "Use a constant. And another:"; got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
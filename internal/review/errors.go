@@ -0,0 +1,94 @@
+package review
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// ErrReviewUnavailable is returned when no configured model could produce a reply,
+// even after retries and falling back to the fallback model
+var ErrReviewUnavailable = errors.New("AI review unavailable")
+
+// ErrFileNotFound is returned when a file doesn't exist at the requested commit
+var ErrFileNotFound = errors.New("file not found")
+
+// APIError is an unsuccessful HTTP response of an AI provider
+type APIError struct {
+	Provider   string
+	StatusCode int
+	RetryAfter time.Duration // from the retry-after header, 0 if absent
+	Message    string
+}
+
+func (e *APIError) Error() string {
+	if e.Message == "" {
+		return fmt.Sprintf("%s API returned status %d", e.Provider, e.StatusCode)
+	}
+	return fmt.Sprintf("%s API returned status %d: %s", e.Provider, e.StatusCode, e.Message)
+}
+
+// Retryable reports whether the request may succeed when sent again: rate limits (429),
+// Anthropic's overloaded status (529) and server errors
+func (e *APIError) Retryable() bool {
+	return e.StatusCode == http.StatusTooManyRequests ||
+		e.StatusCode == http.StatusRequestTimeout ||
+		e.StatusCode >= 500
+}
+
+// newAPIError builds an APIError from a non-200 response
+func newAPIError(provider string, resp *http.Response) *APIError {
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+	return &APIError{
+		Provider:   provider,
+		StatusCode: resp.StatusCode,
+		RetryAfter: parseRetryAfter(resp.Header.Get("retry-after")),
+		Message:    string(body),
+	}
+}
+
+// parseRetryAfter parses a retry-after header given in seconds or as an HTTP date
+func parseRetryAfter(value string) time.Duration {
+	if value == "" {
+		return 0
+	}
+	if seconds, err := strconv.Atoi(value); err == nil && seconds > 0 {
+		return time.Duration(seconds) * time.Second
+	}
+	if at, err := http.ParseTime(value); err == nil {
+		if d := time.Until(at); d > 0 {
+			return d
+		}
+	}
+	return 0
+}
+
+// isRetryable reports whether a provider error is transient
+func isRetryable(err error) bool {
+	var apiErr *APIError
+	if errors.As(err, &apiErr) {
+		return apiErr.Retryable()
+	}
+
+	// Timeouts and connection failures
+	var netErr net.Error
+	return errors.As(err, &netErr)
+}
+
+// isUnavailable reports whether the provider or model can't be used at all: transient
+// failures, rejected credentials (401, 403) and unknown models (404). Other client errors,
+// e.g. a rejected tool schema, are left to the caller, who may ask differently.
+func isUnavailable(err error) bool {
+	var apiErr *APIError
+	if errors.As(err, &apiErr) {
+		switch apiErr.StatusCode {
+		case http.StatusUnauthorized, http.StatusForbidden, http.StatusNotFound:
+			return true
+		}
+	}
+	return isRetryable(err)
+}
@@ -3,81 +3,278 @@ package review
 import (
 	"context"
 	"fmt"
+	"log"
+	"net/http"
+	"net/url"
 	"strings"
 
 	"github.com/google/go-github/v57/github"
 	"golang.org/x/oauth2"
 )
 
+const (
+	// maxListedFiles is the maximum number of files GitHub lists for a PR
+	maxListedFiles = 3000
+
+	// maxFileChanges is the number of changed lines above which a file isn't reviewed
+	maxFileChanges = 500
+)
+
 // GitHubClient handles all GitHub API operations
 type GitHubClient struct {
 	client *github.Client
 }
 
-// NewGitHubClient creates a new GitHub client with the provided token
-func NewGitHubClient(token string) (*GitHubClient, error) {
-	ctx := context.Background()
+// NewGitHubClient creates a new GitHub client with the provided token. apiURL and uploadURL
+// point it to a GitHub Enterprise Server, they are empty for github.com.
+func NewGitHubClient(token, apiURL, uploadURL string) (*GitHubClient, error) {
 	ts := oauth2.StaticTokenSource(
 		&oauth2.Token{AccessToken: token},
 	)
-	tc := oauth2.NewClient(ctx, ts)
+	return NewGitHubClientFromTokenSource(ts, apiURL, uploadURL)
+}
+
+// NewGitHubClientFromTokenSource creates a GitHub client authenticating with the tokens of
+// ts, e.g. the refreshing tokens of an installation
+func NewGitHubClientFromTokenSource(ts oauth2.TokenSource, apiURL, uploadURL string) (*GitHubClient, error) {
+	client, err := newAPIClient(oauth2.NewClient(context.Background(), ts), apiURL, uploadURL)
+	if err != nil {
+		return nil, err
+	}
 
 	return &GitHubClient{
-		client: github.NewClient(tc),
+		client: client,
 	}, nil
 }
 
+// newAPIClient creates a go-github client for github.com, or for the GitHub Enterprise
+// Server at apiURL
+func newAPIClient(httpClient *http.Client, apiURL, uploadURL string) (*github.Client, error) {
+	client := github.NewClient(httpClient)
+	if apiURL == "" {
+		return client, nil
+	}
+
+	// Enterprise Server serves uploads from /api/uploads on the same host by default
+	if uploadURL == "" {
+		u, err := url.Parse(apiURL)
+		if err != nil {
+			return nil, fmt.Errorf("invalid GitHub Enterprise URL %s: %w", apiURL, err)
+		}
+		uploadURL = (&url.URL{Scheme: u.Scheme, Host: u.Host, Path: "/"}).String()
+	}
+	client, err := client.WithEnterpriseURLs(apiURL, uploadURL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid GitHub Enterprise URL %s: %w", apiURL, err)
+	}
+	return client, nil
+}
+
+// GetPullRequest fetches a pull request by number
+func (g *GitHubClient) GetPullRequest(ctx context.Context, owner, repo string, prNumber int) (*github.PullRequest, error) {
+	pr, _, err := g.client.PullRequests.Get(ctx, owner, repo, prNumber)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get PR #%d: %w", prNumber, err)
+	}
+	return pr, nil
+}
+
 // GetPRDiff fetches the diff for a pull request
-func (g *GitHubClient) GetPRDiff(ctx context.Context, owner, repo string, prNumber int) (string, error) {
+func (g *GitHubClient) GetPRDiff(ctx context.Context, owner, repo string, prNumber int) (*PRDiff, error) {
 	// Get the PR files
-	files, _, err := g.client.PullRequests.ListFiles(ctx, owner, repo, prNumber, nil)
+	files, err := g.listPRFiles(ctx, owner, repo, prNumber)
 	if err != nil {
-		return "", fmt.Errorf("failed to get PR files: %w", err)
+		return nil, err
+	}
+
+	return buildDiff(files), nil
+}
+
+// listPRFiles fetches all changed files of a PR, following pagination up to GitHub's cap
+func (g *GitHubClient) listPRFiles(ctx context.Context, owner, repo string, prNumber int) ([]*github.CommitFile, error) {
+	var files []*github.CommitFile
+	opts := &github.ListOptions{PerPage: 100}
+	for {
+		page, resp, err := g.client.PullRequests.ListFiles(ctx, owner, repo, prNumber, opts)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get PR files: %w", err)
+		}
+		files = append(files, page...)
+
+		if resp.NextPage == 0 || len(files) >= maxListedFiles {
+			break
+		}
+		opts.Page = resp.NextPage
+	}
+
+	return files, nil
+}
+
+// GetPRFileDiff fetches the diff of a single file in a pull request
+func (g *GitHubClient) GetPRFileDiff(ctx context.Context, owner, repo string, prNumber int, path string) (*PRDiff, error) {
+	files, err := g.listPRFiles(ctx, owner, repo, prNumber)
+	if err != nil {
+		return nil, err
 	}
 
-	var diffBuilder strings.Builder
 	for _, file := range files {
-		// Skip binary files and very large files
-		if file.GetPatch() == "" || file.GetChanges() > 500 {
-			continue
+		if file.GetFilename() == path {
+			return buildDiff([]*github.CommitFile{file}), nil
+		}
+	}
+
+	return nil, fmt.Errorf("%s is not changed in PR #%d: %w", path, prNumber, ErrFileNotFound)
+}
+
+// GetCompareDiff fetches the diff between two commits, used for incremental reviews
+func (g *GitHubClient) GetCompareDiff(ctx context.Context, owner, repo, base, head string) (*CompareResult, error) {
+	comparison, _, err := g.client.Repositories.CompareCommits(ctx, owner, repo, base, head, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to compare %s...%s: %w", base, head, err)
+	}
+
+	result := &CompareResult{
+		Status:       comparison.GetStatus(),
+		TotalCommits: comparison.GetTotalCommits(),
+		Files:        len(comparison.Files),
+		Diff:         buildDiff(comparison.Files),
+	}
+	for _, file := range comparison.Files {
+		result.Additions += file.GetAdditions()
+		result.Deletions += file.GetDeletions()
+	}
+
+	return result, nil
+}
+
+// LastReviewedCommit returns the commit SHA of the most recent Cyclone review on a PR,
+// or an empty string if Cyclone has not reviewed it yet. Only reviews posted as login
+// count, so a human quoting Cyclone's heading doesn't move the base of incremental reviews.
+func (g *GitHubClient) LastReviewedCommit(ctx context.Context, owner, repo string, prNumber int, login string) (string, error) {
+	var lastSHA string
+	opts := &github.ListOptions{PerPage: 100}
+	for {
+		reviews, resp, err := g.client.PullRequests.ListReviews(ctx, owner, repo, prNumber, opts)
+		if err != nil {
+			return "", fmt.Errorf("failed to list reviews: %w", err)
+		}
+
+		// Reviews are returned in chronological order, so the last match wins
+		for _, r := range reviews {
+			if r.GetUser().GetLogin() == login && strings.HasPrefix(r.GetBody(), ReviewHeader) {
+				lastSHA = r.GetCommitID()
+			}
 		}
 
-		// Additional check for binary files by file extension
+		if resp.NextPage == 0 {
+			break
+		}
+		opts.Page = resp.NextPage
+	}
+
+	return lastSHA, nil
+}
+
+// buildDiff renders the patches of the given files into the diff sent to the AI
+// and parses their hunks. Files that can't be reviewed are recorded with the reason.
+func buildDiff(files []*github.CommitFile) *PRDiff {
+	diff := &PRDiff{Files: make(map[string]*FileDiff)}
+
+	for _, file := range files {
 		filename := file.GetFilename()
-		if isBinaryFile(filename) {
+
+		// Skip binary files, files without a patch and very large files
+		switch {
+		case isBinaryFile(filename):
+			diff.Skipped = append(diff.Skipped, SkippedFile{Path: filename, Reason: SkipReasonBinary})
+			continue
+		case file.GetPatch() == "":
+			diff.Skipped = append(diff.Skipped, SkippedFile{Path: filename, Reason: SkipReasonNoPatch})
+			continue
+		case file.GetChanges() > maxFileChanges:
+			diff.Skipped = append(diff.Skipped, SkippedFile{Path: filename, Reason: SkipReasonTooLarge})
 			continue
 		}
 
+		hunks, err := ParsePatch(file.GetPatch())
+		if err != nil {
+			log.Printf("Error parsing patch of %s: %v", filename, err)
+		}
+		diff.Files[filename] = &FileDiff{
+			Path:  filename,
+			Patch: file.GetPatch(),
+			Hunks: hunks,
+		}
+		diff.order = append(diff.order, filename)
+	}
+
+	diff.render()
+	return diff
+}
+
+// render builds the diff text sent to the AI from the files of the diff
+func (d *PRDiff) render() {
+	var diffBuilder strings.Builder
+	for _, filename := range d.order {
+		file, ok := d.Files[filename]
+		if !ok {
+			continue
+		}
 		diffBuilder.WriteString(fmt.Sprintf("=== %s ===\n", filename))
-		diffBuilder.WriteString(file.GetPatch())
+		diffBuilder.WriteString(file.Patch)
 		diffBuilder.WriteString("\n\n")
 	}
 
-	return diffBuilder.String(), nil
+	// Let the AI know the diff is incomplete so the summary doesn't pretend otherwise
+	if len(d.Skipped) > 0 {
+		diffBuilder.WriteString("=== Files changed but not included in this diff ===\n")
+		for _, skipped := range d.Skipped {
+			diffBuilder.WriteString(fmt.Sprintf("- %s (%s)\n", skipped.Path, skipped.Reason))
+		}
+	}
+
+	d.Text = diffBuilder.String()
 }
 
-// PostReview posts a complete PR review with line-specific comments
-func (g *GitHubClient) PostReview(ctx context.Context, owner, repo string, prNumber int, review ReviewResult) error {
+// PostReview posts a complete PR review with line-specific comments. event is one of
+// ReviewEventComment, ReviewEventApprove or ReviewEventRequestChanges.
+func (g *GitHubClient) PostReview(ctx context.Context, owner, repo string, prNumber int, commitID, event string, review ReviewResult) error {
 	// Prepare review comments for line-specific feedback
 	var reviewComments []*github.DraftReviewComment
 
 	for _, comment := range review.Comments {
-		reviewComments = append(reviewComments, &github.DraftReviewComment{
+		draft := &github.DraftReviewComment{
 			Path: github.String(comment.Path),
 			Line: github.Int(comment.Line),
 			Side: github.String(comment.Side),
 			Body: github.String(comment.Body),
-		})
+		}
+		if comment.StartLine > 0 {
+			startSide := comment.StartSide
+			if startSide == "" {
+				startSide = comment.Side
+			}
+			draft.StartLine = github.Int(comment.StartLine)
+			draft.StartSide = github.String(startSide)
+		}
+		reviewComments = append(reviewComments, draft)
 	}
 
 	// Create the review
 	reviewRequest := &github.PullRequestReviewRequest{
+		CommitID: github.String(commitID),
 		Body:     github.String(review.Summary),
-		Event:    github.String("COMMENT"), // Can be COMMENT, APPROVE, or REQUEST_CHANGES
+		Event:    github.String(event),
 		Comments: reviewComments,
 	}
 
-	_, _, err := g.client.PullRequests.CreateReview(ctx, owner, repo, prNumber, reviewRequest)
+	_, resp, err := g.client.PullRequests.CreateReview(ctx, owner, repo, prNumber, reviewRequest)
+	if err != nil && event != ReviewEventComment && resp != nil && resp.StatusCode == http.StatusUnprocessableEntity {
+		// e.g. a personal access token can't approve or request changes on its own PR
+		log.Printf("Cannot post review on PR #%d as %s, posting it as a comment: %v", prNumber, event, err)
+		reviewRequest.Event = github.String(ReviewEventComment)
+		_, _, err = g.client.PullRequests.CreateReview(ctx, owner, repo, prNumber, reviewRequest)
+	}
 	if err != nil {
 		return fmt.Errorf("failed to create review: %w", err)
 	}
@@ -85,6 +282,73 @@ func (g *GitHubClient) PostReview(ctx context.Context, owner, repo string, prNum
 	return nil
 }
 
+// DismissChangeRequests dismisses Cyclone's earlier reviews on a PR that requested changes
+func (g *GitHubClient) DismissChangeRequests(ctx context.Context, owner, repo string, prNumber int, message string) error {
+	opts := &github.ListOptions{PerPage: 100}
+	for {
+		reviews, resp, err := g.client.PullRequests.ListReviews(ctx, owner, repo, prNumber, opts)
+		if err != nil {
+			return fmt.Errorf("failed to list reviews: %w", err)
+		}
+
+		for _, r := range reviews {
+			if r.GetState() != "CHANGES_REQUESTED" || !strings.HasPrefix(r.GetBody(), ReviewHeader) {
+				continue
+			}
+			dismissal := &github.PullRequestReviewDismissalRequest{Message: github.String(message)}
+			if _, _, err := g.client.PullRequests.DismissReview(ctx, owner, repo, prNumber, r.GetID(), dismissal); err != nil {
+				return fmt.Errorf("failed to dismiss review %d: %w", r.GetID(), err)
+			}
+			log.Printf("Dismissed review %d requesting changes on PR #%d", r.GetID(), prNumber)
+		}
+
+		if resp.NextPage == 0 {
+			break
+		}
+		opts.Page = resp.NextPage
+	}
+
+	return nil
+}
+
+// GetFileContent returns the content of a file at a commit
+func (g *GitHubClient) GetFileContent(ctx context.Context, owner, repo, path, ref string) (string, error) {
+	file, _, resp, err := g.client.Repositories.GetContents(ctx, owner, repo, path, &github.RepositoryContentGetOptions{Ref: ref})
+	if resp != nil && resp.StatusCode == http.StatusNotFound {
+		return "", fmt.Errorf("failed to get content of %s: %w", path, ErrFileNotFound)
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to get content of %s: %w", path, err)
+	}
+	if file == nil {
+		return "", fmt.Errorf("%s is a directory", path)
+	}
+
+	content, err := file.GetContent()
+	if err != nil {
+		return "", fmt.Errorf("failed to decode content of %s: %w", path, err)
+	}
+
+	return content, nil
+}
+
+// ListDirectory returns the paths of the files in a directory at a commit
+func (g *GitHubClient) ListDirectory(ctx context.Context, owner, repo, dir, ref string) ([]string, error) {
+	_, entries, _, err := g.client.Repositories.GetContents(ctx, owner, repo, dir, &github.RepositoryContentGetOptions{Ref: ref})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list %s: %w", dir, err)
+	}
+
+	var files []string
+	for _, entry := range entries {
+		if entry.GetType() == "file" {
+			files = append(files, entry.GetPath())
+		}
+	}
+
+	return files, nil
+}
+
 // PostComment posts a simple comment to a PR (used for skip messages)
 func (g *GitHubClient) PostComment(ctx context.Context, owner, repo string, prNumber int, body string) error {
 	comment := &github.IssueComment{
@@ -99,6 +363,164 @@ func (g *GitHubClient) PostComment(ctx context.Context, owner, repo string, prNu
 	return nil
 }
 
+// ReplyToReviewComment posts a reply in the thread of a line-specific review comment
+func (g *GitHubClient) ReplyToReviewComment(ctx context.Context, owner, repo string, prNumber int, commentID int64, body string) error {
+	_, _, err := g.client.PullRequests.CreateCommentInReplyTo(ctx, owner, repo, prNumber, body, commentID)
+	if err != nil {
+		return fmt.Errorf("failed to reply to review comment: %w", err)
+	}
+
+	return nil
+}
+
+// GetReviewThread returns the root review comment and all replies to it, oldest first
+func (g *GitHubClient) GetReviewThread(ctx context.Context, owner, repo string, prNumber int, rootID int64) ([]*github.PullRequestComment, error) {
+	var thread []*github.PullRequestComment
+	opts := &github.PullRequestListCommentsOptions{
+		Sort:        "created",
+		Direction:   "asc",
+		ListOptions: github.ListOptions{PerPage: 100},
+	}
+	for {
+		comments, resp, err := g.client.PullRequests.ListComments(ctx, owner, repo, prNumber, opts)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list review comments: %w", err)
+		}
+
+		for _, c := range comments {
+			if c.GetID() == rootID || c.GetInReplyTo() == rootID {
+				thread = append(thread, c)
+			}
+		}
+
+		if resp.NextPage == 0 {
+			break
+		}
+		opts.Page = resp.NextPage
+	}
+
+	return thread, nil
+}
+
+// GetAuthenticatedLogin returns the login of the user the client is authenticated as.
+// Only works for personal access tokens, not installation tokens.
+func (g *GitHubClient) GetAuthenticatedLogin(ctx context.Context) (string, error) {
+	user, _, err := g.client.Users.Get(ctx, "")
+	if err != nil {
+		return "", fmt.Errorf("failed to get authenticated user: %w", err)
+	}
+	return user.GetLogin(), nil
+}
+
+// HasWriteAccess reports whether a user can push to the repository
+func (g *GitHubClient) HasWriteAccess(ctx context.Context, owner, repo, user string) (bool, error) {
+	level, _, err := g.client.Repositories.GetPermissionLevel(ctx, owner, repo, user)
+	if err != nil {
+		return false, fmt.Errorf("failed to get permission level for %s: %w", user, err)
+	}
+
+	switch level.GetPermission() {
+	case "admin", "maintain", "write":
+		return true, nil
+	default:
+		return false, nil
+	}
+}
+
+// ReactToIssueComment adds a reaction (e.g. "eyes", "+1", "confused") to a PR conversation comment
+func (g *GitHubClient) ReactToIssueComment(ctx context.Context, owner, repo string, commentID int64, content string) error {
+	_, _, err := g.client.Reactions.CreateIssueCommentReaction(ctx, owner, repo, commentID, content)
+	if err != nil {
+		return fmt.Errorf("failed to add reaction: %w", err)
+	}
+
+	return nil
+}
+
+// ReactToReviewComment adds a reaction to a line-specific review comment
+func (g *GitHubClient) ReactToReviewComment(ctx context.Context, owner, repo string, commentID int64, content string) error {
+	_, _, err := g.client.Reactions.CreatePullRequestCommentReaction(ctx, owner, repo, commentID, content)
+	if err != nil {
+		return fmt.Errorf("failed to add reaction: %w", err)
+	}
+
+	return nil
+}
+
+// AddLabel adds a label to a PR
+func (g *GitHubClient) AddLabel(ctx context.Context, owner, repo string, prNumber int, label string) error {
+	_, _, err := g.client.Issues.AddLabelsToIssue(ctx, owner, repo, prNumber, []string{label})
+	if err != nil {
+		return fmt.Errorf("failed to add label %s: %w", label, err)
+	}
+
+	return nil
+}
+
+// RemoveLabel removes a label from a PR, ignoring labels that are not set
+func (g *GitHubClient) RemoveLabel(ctx context.Context, owner, repo string, prNumber int, label string) error {
+	resp, err := g.client.Issues.RemoveLabelForIssue(ctx, owner, repo, prNumber, label)
+	if err != nil {
+		if resp != nil && resp.StatusCode == http.StatusNotFound {
+			return nil
+		}
+		return fmt.Errorf("failed to remove label %s: %w", label, err)
+	}
+
+	return nil
+}
+
+// CreateCheckRun creates Cyclone's check run on a commit in the in_progress state and returns its ID
+func (g *GitHubClient) CreateCheckRun(ctx context.Context, owner, repo, headSHA string) (int64, error) {
+	checkRun, _, err := g.client.Checks.CreateCheckRun(ctx, owner, repo, github.CreateCheckRunOptions{
+		Name:    CheckRunName,
+		HeadSHA: headSHA,
+		Status:  github.String("in_progress"),
+		Output: &github.CheckRunOutput{
+			Title:   github.String("Review in progress"),
+			Summary: github.String("🌪️ Cyclone is reviewing the changes..."),
+		},
+	})
+	if err != nil {
+		return 0, fmt.Errorf("failed to create check run: %w", err)
+	}
+
+	return checkRun.GetID(), nil
+}
+
+// CompleteCheckRun completes a check run with a conclusion and annotations. GitHub accepts
+// at most 50 annotations per request, so the remaining ones are added by further updates.
+func (g *GitHubClient) CompleteCheckRun(ctx context.Context, owner, repo string, checkRunID int64, conclusion, title, summary string, annotations []*github.CheckRunAnnotation) error {
+	for {
+		batch := annotations
+		if len(batch) > maxAnnotationsPerRequest {
+			batch = batch[:maxAnnotationsPerRequest]
+		}
+		annotations = annotations[len(batch):]
+
+		opts := github.UpdateCheckRunOptions{
+			Name: CheckRunName,
+			Output: &github.CheckRunOutput{
+				Title:       github.String(title),
+				Summary:     github.String(summary),
+				Annotations: batch,
+			},
+		}
+		if len(annotations) == 0 {
+			// Only complete the run with the last batch so it isn't shown as done too early
+			opts.Status = github.String("completed")
+			opts.Conclusion = github.String(conclusion)
+		}
+
+		if _, _, err := g.client.Checks.UpdateCheckRun(ctx, owner, repo, checkRunID, opts); err != nil {
+			return fmt.Errorf("failed to update check run: %w", err)
+		}
+		if len(annotations) == 0 {
+			return nil
+		}
+	}
+}
+
 // isBinaryFile checks if a file is likely binary based on its extension
 func isBinaryFile(filename string) bool {
 	binaryExtensions := []string{
@@ -3,51 +3,68 @@ package review
 import (
 	"context"
 	"crypto/rsa"
-	"crypto/x509"
-	"encoding/pem"
 	"fmt"
-	"io/ioutil"
+	"sync"
 	"time"
 
 	"github.com/golang-jwt/jwt/v4"
 	"github.com/google/go-github/v57/github"
 	"golang.org/x/oauth2"
+
+	"cyclone/internal/config"
 )
 
+// installationTokenTimeout bounds the request creating an installation token
+const installationTokenTimeout = 30 * time.Second
+
 // GitHubAppAuth handles GitHub App authentication
 type GitHubAppAuth struct {
-	appID      int64
-	privateKey *rsa.PrivateKey
-	client     *github.Client
+	appID     int64
+	apiURL    string // of a GitHub Enterprise Server, "" for github.com
+	uploadURL string
+	client    *github.Client
+
+	keyMu          sync.RWMutex
+	privateKey     *rsa.PrivateKey
+	privateKeyPath string   // "" if the key was given inline
+	keyFile        fileStat // of privateKeyPath when the key was last loaded
+
+	mu           sync.Mutex
+	tokenSources map[int64]*installationTokenSource
 }
 
-// NewGitHubAppAuth creates a new GitHub App authenticator
-func NewGitHubAppAuth(appID int64, privateKeyPath string) (*GitHubAppAuth, error) {
-	// Read private key
-	keyData, err := ioutil.ReadFile(privateKeyPath)
+// NewGitHubAppAuth creates a new GitHub App authenticator with the PEM private key at
+// privateKeyPath, or if that's empty the inline PEM or base64 encoded PEM privateKey.
+// apiURL and uploadURL point it to a GitHub Enterprise Server, they are empty for github.com.
+func NewGitHubAppAuth(appID int64, privateKeyPath, privateKey, apiURL, uploadURL string) (*GitHubAppAuth, error) {
+	// Create client for JWT requests, authenticated per request
+	client, err := newAPIClient(nil, apiURL, uploadURL)
 	if err != nil {
-		return nil, fmt.Errorf("failed to read private key: %w", err)
+		return nil, err
 	}
 
-	// Parse private key
-	block, _ := pem.Decode(keyData)
-	if block == nil {
-		return nil, fmt.Errorf("failed to decode PEM block")
+	auth := &GitHubAppAuth{
+		appID:          appID,
+		privateKeyPath: privateKeyPath,
+		apiURL:         apiURL,
+		uploadURL:      uploadURL,
+		client:         client,
+		tokenSources:   make(map[int64]*installationTokenSource),
 	}
 
-	privateKey, err := x509.ParsePKCS1PrivateKey(block.Bytes)
-	if err != nil {
-		return nil, fmt.Errorf("failed to parse private key: %w", err)
+	if privateKeyPath != "" {
+		if err := auth.loadPrivateKeyFile(); err != nil {
+			return nil, err
+		}
+		return auth, nil
 	}
 
-	// Create client for JWT requests
-	client := github.NewClient(nil)
-
-	return &GitHubAppAuth{
-		appID:      appID,
-		privateKey: privateKey,
-		client:     client,
-	}, nil
+	key, err := parsePrivateKey([]byte(privateKey))
+	if err != nil {
+		return nil, err
+	}
+	auth.privateKey = key
+	return auth, nil
 }
 
 // GenerateJWT creates a JWT for GitHub App authentication
@@ -59,28 +76,106 @@ func (auth *GitHubAppAuth) GenerateJWT() (string, error) {
 		Issuer:    fmt.Sprintf("%d", auth.appID),
 	}
 
+	auth.keyMu.RLock()
+	defer auth.keyMu.RUnlock()
+
 	token := jwt.NewWithClaims(jwt.SigningMethodRS256, claims)
 	return token.SignedString(auth.privateKey)
 }
 
-// GetInstallationToken gets an access token for a specific installation
+// GetInstallationToken gets an access token for a specific installation, reusing the
+// previous one until shortly before it expires
 func (auth *GitHubAppAuth) GetInstallationToken(ctx context.Context, installationID int64) (string, error) {
+	token, err := auth.TokenSource(installationID).Token()
+	if err != nil {
+		return "", err
+	}
+	return token.AccessToken, nil
+}
+
+// NewInstallationClient creates a client of an installation that refreshes its access token
+// when it is about to expire
+func (auth *GitHubAppAuth) NewInstallationClient(installationID int64) (*GitHubClient, error) {
+	return NewGitHubClientFromTokenSource(auth.TokenSource(installationID), auth.apiURL, auth.uploadURL)
+}
+
+// TokenSource returns the access tokens of an installation as an oauth2.TokenSource, so a
+// client of the installation can be kept for longer than a token lasts
+func (auth *GitHubAppAuth) TokenSource(installationID int64) oauth2.TokenSource {
+	auth.mu.Lock()
+	defer auth.mu.Unlock()
+
+	ts, ok := auth.tokenSources[installationID]
+	if !ok {
+		ts = &installationTokenSource{auth: auth, installationID: installationID}
+		auth.tokenSources[installationID] = ts
+	}
+	return ts
+}
+
+// createInstallationToken creates a new access token for an installation
+func (auth *GitHubAppAuth) createInstallationToken(ctx context.Context, installationID int64) (*oauth2.Token, error) {
 	// Generate JWT
 	jwt, err := auth.GenerateJWT()
 	if err != nil {
-		return "", fmt.Errorf("failed to generate JWT: %w", err)
+		return nil, fmt.Errorf("failed to generate JWT: %w", err)
 	}
 
-	// Create authenticated client with JWT
-	ts := oauth2.StaticTokenSource(&oauth2.Token{AccessToken: jwt})
-	tc := oauth2.NewClient(ctx, ts)
-	client := github.NewClient(tc)
+	// Get installation access token, authenticated as the App
+	token, _, err := auth.client.WithAuthToken(jwt).Apps.CreateInstallationToken(ctx, installationID, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create installation token: %w", err)
+	}
+
+	return &oauth2.Token{
+		AccessToken: token.GetToken(),
+		TokenType:   "Bearer",
+		Expiry:      token.GetExpiresAt().Time,
+	}, nil
+}
+
+// installationTokenSource caches the access token of an installation, creating a new one
+// shortly before it expires. Concurrent callers wait for a single refresh.
+type installationTokenSource struct {
+	auth           *GitHubAppAuth
+	installationID int64
+
+	mu    sync.Mutex
+	token *oauth2.Token
+}
+
+// Token returns the cached access token, or a new one if it is about to expire
+func (ts *installationTokenSource) Token() (*oauth2.Token, error) {
+	ts.mu.Lock()
+	defer ts.mu.Unlock()
+
+	if ts.token != nil && time.Until(ts.token.Expiry) > config.INSTALLATION_TOKEN_REFRESH_MARGIN {
+		return ts.token, nil
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), installationTokenTimeout)
+	defer cancel()
+
+	token, err := ts.auth.createInstallationToken(ctx, ts.installationID)
+	if err != nil {
+		return nil, err
+	}
+	ts.token = token
+	return token, nil
+}
+
+// GetAppSlug returns the URL-friendly name of the GitHub App. The App comments
+// as "<slug>[bot]".
+func (auth *GitHubAppAuth) GetAppSlug(ctx context.Context) (string, error) {
+	jwt, err := auth.GenerateJWT()
+	if err != nil {
+		return "", fmt.Errorf("failed to generate JWT: %w", err)
+	}
 
-	// Get installation access token
-	token, _, err := client.Apps.CreateInstallationToken(ctx, installationID, nil)
+	app, _, err := auth.client.WithAuthToken(jwt).Apps.Get(ctx, "")
 	if err != nil {
-		return "", fmt.Errorf("failed to create installation token: %w", err)
+		return "", fmt.Errorf("failed to get app: %w", err)
 	}
 
-	return token.GetToken(), nil
+	return app.GetSlug(), nil
 }
@@ -0,0 +1,193 @@
+package review
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"cyclone/internal/config"
+)
+
+// fakeTokenEndpoint serves GitHub's installation token endpoint, handing out tokens that
+// expire after lifetime
+type fakeTokenEndpoint struct {
+	lifetime time.Duration
+	status   int           // response status, 0 for 201 Created
+	delay    time.Duration // before answering
+	requests atomic.Int32
+}
+
+func (f *fakeTokenEndpoint) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	n := f.requests.Add(1)
+	time.Sleep(f.delay)
+
+	if !strings.HasPrefix(r.Header.Get("Authorization"), "Bearer ") {
+		http.Error(w, `{"message": "missing JWT"}`, http.StatusUnauthorized)
+		return
+	}
+	if f.status != 0 {
+		http.Error(w, `{"message": "installation suspended"}`, f.status)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(map[string]any{
+		"token":      fmt.Sprintf("ghs_%s_%d", r.PathValue("id"), n),
+		"expires_at": time.Now().Add(f.lifetime).UTC().Format(time.RFC3339),
+	})
+}
+
+// newTestAppAuth creates a GitHub App authenticator against a fake GitHub Enterprise Server
+// serving the token endpoint
+func newTestAppAuth(t *testing.T, endpoint *fakeTokenEndpoint) *GitHubAppAuth {
+	t.Helper()
+
+	mux := http.NewServeMux()
+	mux.Handle("POST /api/v3/app/installations/{id}/access_tokens", endpoint)
+	server := httptest.NewServer(mux)
+	t.Cleanup(server.Close)
+
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("failed to generate key: %v", err)
+	}
+	keyPEM := pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(key)})
+
+	auth, err := NewGitHubAppAuth(42, "", string(keyPEM), server.URL+"/", "")
+	if err != nil {
+		t.Fatalf("NewGitHubAppAuth: %v", err)
+	}
+	return auth
+}
+
+func TestInstallationTokenCached(t *testing.T) {
+	endpoint := &fakeTokenEndpoint{lifetime: time.Hour}
+	ts := newTestAppAuth(t, endpoint).TokenSource(7)
+
+	first, err := ts.Token()
+	if err != nil {
+		t.Fatalf("Token: %v", err)
+	}
+	if first.AccessToken != "ghs_7_1" {
+		t.Errorf("got token %q, want ghs_7_1", first.AccessToken)
+	}
+
+	second, err := ts.Token()
+	if err != nil {
+		t.Fatalf("Token: %v", err)
+	}
+	if second.AccessToken != first.AccessToken {
+		t.Errorf("got token %q, want the cached %q", second.AccessToken, first.AccessToken)
+	}
+	if got := endpoint.requests.Load(); got != 1 {
+		t.Errorf("got %d token requests, want 1", got)
+	}
+}
+
+func TestInstallationTokenRefreshedWithinMargin(t *testing.T) {
+	endpoint := &fakeTokenEndpoint{lifetime: config.INSTALLATION_TOKEN_REFRESH_MARGIN - time.Minute}
+	ts := newTestAppAuth(t, endpoint).TokenSource(7)
+
+	first, err := ts.Token()
+	if err != nil {
+		t.Fatalf("Token: %v", err)
+	}
+	second, err := ts.Token()
+	if err != nil {
+		t.Fatalf("Token: %v", err)
+	}
+
+	if second.AccessToken == first.AccessToken {
+		t.Errorf("token %q expiring within the refresh margin was reused", first.AccessToken)
+	}
+	if got := endpoint.requests.Load(); got != 2 {
+		t.Errorf("got %d token requests, want 2", got)
+	}
+}
+
+func TestInstallationTokenSourcesPerInstallation(t *testing.T) {
+	endpoint := &fakeTokenEndpoint{lifetime: time.Hour}
+	auth := newTestAppAuth(t, endpoint)
+
+	if auth.TokenSource(7) != auth.TokenSource(7) {
+		t.Errorf("got different token sources for the same installation")
+	}
+
+	first, err := auth.GetInstallationToken(context.Background(), 7)
+	if err != nil {
+		t.Fatalf("GetInstallationToken: %v", err)
+	}
+	second, err := auth.GetInstallationToken(context.Background(), 8)
+	if err != nil {
+		t.Fatalf("GetInstallationToken: %v", err)
+	}
+	if !strings.HasPrefix(first, "ghs_7_") || !strings.HasPrefix(second, "ghs_8_") {
+		t.Errorf("got tokens %q and %q, want one per installation", first, second)
+	}
+}
+
+func TestInstallationTokenConcurrentCallersShareFetch(t *testing.T) {
+	endpoint := &fakeTokenEndpoint{lifetime: time.Hour, delay: 50 * time.Millisecond}
+	ts := newTestAppAuth(t, endpoint).TokenSource(7)
+
+	const callers = 10
+	tokens := make([]string, callers)
+	errs := make([]error, callers)
+	start := make(chan struct{})
+	var wg sync.WaitGroup
+	for i := 0; i < callers; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			<-start
+			token, err := ts.Token()
+			if err == nil {
+				tokens[i] = token.AccessToken
+			}
+			errs[i] = err
+		}(i)
+	}
+	close(start)
+	wg.Wait()
+
+	for i := range tokens {
+		if errs[i] != nil {
+			t.Fatalf("caller %d: Token: %v", i, errs[i])
+		}
+		if tokens[i] != tokens[0] {
+			t.Errorf("caller %d got token %q, want %q", i, tokens[i], tokens[0])
+		}
+	}
+	if got := endpoint.requests.Load(); got != 1 {
+		t.Errorf("got %d token requests, want 1", got)
+	}
+}
+
+func TestInstallationTokenEndpointError(t *testing.T) {
+	endpoint := &fakeTokenEndpoint{status: http.StatusForbidden}
+	auth := newTestAppAuth(t, endpoint)
+
+	if _, err := auth.TokenSource(7).Token(); err == nil || !strings.Contains(err.Error(), "403") {
+		t.Errorf("got error %v, want the endpoint's 403", err)
+	}
+	if _, err := auth.GetInstallationToken(context.Background(), 7); err == nil {
+		t.Errorf("GetInstallationToken succeeded, want the endpoint's error")
+	}
+
+	// Failures aren't cached, the next call asks again
+	if got := endpoint.requests.Load(); got != 2 {
+		t.Errorf("got %d token requests, want 2", got)
+	}
+}
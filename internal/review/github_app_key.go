@@ -0,0 +1,124 @@
+package review
+
+import (
+	"bytes"
+	"context"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"fmt"
+	"log"
+	"os"
+	"time"
+)
+
+// fileStat is what tells a rotated key file apart from the one loaded before
+type fileStat struct {
+	modTime time.Time
+	size    int64
+}
+
+// parsePrivateKey parses an RSA private key in PKCS#1 or PKCS#8 PEM format. The PEM may be
+// base64 encoded, and may have escaped newlines as some secret managers inject them.
+func parsePrivateKey(data []byte) (*rsa.PrivateKey, error) {
+	data = bytes.TrimSpace(data)
+	if !bytes.Contains(data, []byte("-----BEGIN")) {
+		decoded, err := base64.StdEncoding.DecodeString(string(data))
+		if err != nil {
+			return nil, fmt.Errorf("private key is neither PEM nor base64 encoded PEM")
+		}
+		data = decoded
+	}
+	data = bytes.ReplaceAll(data, []byte(`\n`), []byte("\n"))
+
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, fmt.Errorf("failed to decode PEM block")
+	}
+
+	switch block.Type {
+	case "RSA PRIVATE KEY":
+		key, err := x509.ParsePKCS1PrivateKey(block.Bytes)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse PKCS#1 private key: %w", err)
+		}
+		return key, nil
+
+	case "PRIVATE KEY":
+		key, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse PKCS#8 private key: %w", err)
+		}
+		rsaKey, ok := key.(*rsa.PrivateKey)
+		if !ok {
+			return nil, fmt.Errorf("private key is a %T, GitHub Apps use RSA keys", key)
+		}
+		return rsaKey, nil
+
+	default:
+		return nil, fmt.Errorf("unsupported PEM block %q", block.Type)
+	}
+}
+
+// loadPrivateKeyFile (re)loads the private key from its file
+func (auth *GitHubAppAuth) loadPrivateKeyFile() error {
+	info, err := os.Stat(auth.privateKeyPath)
+	if err != nil {
+		return fmt.Errorf("failed to read private key: %w", err)
+	}
+	data, err := os.ReadFile(auth.privateKeyPath)
+	if err != nil {
+		return fmt.Errorf("failed to read private key: %w", err)
+	}
+
+	key, err := parsePrivateKey(data)
+	if err != nil {
+		return err
+	}
+
+	auth.keyMu.Lock()
+	defer auth.keyMu.Unlock()
+	auth.privateKey = key
+	auth.keyFile = fileStat{modTime: info.ModTime(), size: info.Size()}
+	return nil
+}
+
+// WatchPrivateKey polls the private key file until ctx is done and loads the key again
+// when the file changes, so a rotated key is used without a restart. A file that can't be
+// read or parsed is logged and the previous key kept. Inline keys aren't watched.
+func (auth *GitHubAppAuth) WatchPrivateKey(ctx context.Context, interval time.Duration) {
+	if auth.privateKeyPath == "" {
+		return
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		info, err := os.Stat(auth.privateKeyPath)
+		if err != nil {
+			log.Printf("Error checking private key %s: %v", auth.privateKeyPath, err)
+			continue
+		}
+
+		auth.keyMu.RLock()
+		loaded := auth.keyFile
+		auth.keyMu.RUnlock()
+		if info.ModTime().Equal(loaded.modTime) && info.Size() == loaded.size {
+			continue
+		}
+
+		if err := auth.loadPrivateKeyFile(); err != nil {
+			log.Printf("Error loading rotated private key, keeping the previous one: %v", err)
+			continue
+		}
+		log.Printf("Loaded rotated private key from %s", auth.privateKeyPath)
+	}
+}
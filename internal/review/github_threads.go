@@ -0,0 +1,168 @@
+package review
+
+import (
+	"context"
+	"fmt"
+	"strings"
+)
+
+// ReviewThread is a line comment thread on a PR, as far as Cyclone needs it to avoid
+// repeating itself: where it is, its state and its root comment
+type ReviewThread struct {
+	Path         string
+	Line         int // 0 if the thread is outdated
+	OriginalLine int
+	IsResolved   bool
+	IsOutdated   bool
+
+	RootID          string // GraphQL node ID of the root comment
+	RootAuthor      string
+	RootBody        string
+	RootIsMinimized bool
+}
+
+const reviewThreadsQuery = `query($owner: String!, $repo: String!, $number: Int!, $cursor: String) {
+  repository(owner: $owner, name: $repo) {
+    pullRequest(number: $number) {
+      reviewThreads(first: 100, after: $cursor) {
+        pageInfo { hasNextPage endCursor }
+        nodes {
+          path
+          line
+          originalLine
+          isResolved
+          isOutdated
+          comments(first: 1) {
+            nodes { id body isMinimized author { login } }
+          }
+        }
+      }
+    }
+  }
+}`
+
+const minimizeCommentMutation = `mutation($id: ID!, $classifier: ReportedContentClassifiers!) {
+  minimizeComment(input: {subjectId: $id, classifier: $classifier}) {
+    minimizedComment { isMinimized }
+  }
+}`
+
+type reviewThreadsResponse struct {
+	Repository struct {
+		PullRequest struct {
+			ReviewThreads struct {
+				PageInfo struct {
+					HasNextPage bool   `json:"hasNextPage"`
+					EndCursor   string `json:"endCursor"`
+				} `json:"pageInfo"`
+				Nodes []struct {
+					Path         string `json:"path"`
+					Line         int    `json:"line"`
+					OriginalLine int    `json:"originalLine"`
+					IsResolved   bool   `json:"isResolved"`
+					IsOutdated   bool   `json:"isOutdated"`
+					Comments     struct {
+						Nodes []struct {
+							ID          string `json:"id"`
+							Body        string `json:"body"`
+							IsMinimized bool   `json:"isMinimized"`
+							Author      struct {
+								Login string `json:"login"`
+							} `json:"author"`
+						} `json:"nodes"`
+					} `json:"comments"`
+				} `json:"nodes"`
+			} `json:"reviewThreads"`
+		} `json:"pullRequest"`
+	} `json:"repository"`
+}
+
+type graphQLResponse[T any] struct {
+	Data   T `json:"data"`
+	Errors []struct {
+		Message string `json:"message"`
+	} `json:"errors"`
+}
+
+// graphQL runs a query against GitHub's GraphQL API, which has the state of review
+// threads that the REST API doesn't expose
+func graphQL[T any](ctx context.Context, g *GitHubClient, query string, variables map[string]any) (*T, error) {
+	req, err := g.client.NewRequest("POST", g.graphQLURL(), map[string]any{"query": query, "variables": variables})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create GraphQL request: %w", err)
+	}
+
+	var resp graphQLResponse[T]
+	if _, err := g.client.Do(ctx, req, &resp); err != nil {
+		return nil, fmt.Errorf("GraphQL request failed: %w", err)
+	}
+	if len(resp.Errors) > 0 {
+		messages := make([]string, 0, len(resp.Errors))
+		for _, e := range resp.Errors {
+			messages = append(messages, e.Message)
+		}
+		return nil, fmt.Errorf("GraphQL request failed: %s", strings.Join(messages, "; "))
+	}
+
+	return &resp.Data, nil
+}
+
+// graphQLURL returns the GraphQL endpoint, which GitHub Enterprise Server serves from
+// /api/graphql rather than next to the REST API at /api/v3
+func (g *GitHubClient) graphQLURL() string {
+	base := g.client.BaseURL
+	if !strings.HasSuffix(base.Path, "/api/v3/") {
+		return "graphql"
+	}
+	u := *base
+	u.Path = strings.TrimSuffix(base.Path, "v3/") + "graphql"
+	return u.String()
+}
+
+// ListReviewThreads returns all line comment threads of a PR
+func (g *GitHubClient) ListReviewThreads(ctx context.Context, owner, repo string, prNumber int) ([]ReviewThread, error) {
+	var threads []ReviewThread
+	variables := map[string]any{"owner": owner, "repo": repo, "number": prNumber, "cursor": nil}
+	for {
+		data, err := graphQL[reviewThreadsResponse](ctx, g, reviewThreadsQuery, variables)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list review threads: %w", err)
+		}
+
+		page := data.Repository.PullRequest.ReviewThreads
+		for _, node := range page.Nodes {
+			if len(node.Comments.Nodes) == 0 {
+				continue
+			}
+			root := node.Comments.Nodes[0]
+			threads = append(threads, ReviewThread{
+				Path:            node.Path,
+				Line:            node.Line,
+				OriginalLine:    node.OriginalLine,
+				IsResolved:      node.IsResolved,
+				IsOutdated:      node.IsOutdated,
+				RootID:          root.ID,
+				RootAuthor:      root.Author.Login,
+				RootBody:        root.Body,
+				RootIsMinimized: root.IsMinimized,
+			})
+		}
+
+		if !page.PageInfo.HasNextPage {
+			break
+		}
+		variables["cursor"] = page.PageInfo.EndCursor
+	}
+
+	return threads, nil
+}
+
+// MinimizeOutdatedComment hides a comment on the PR as outdated
+func (g *GitHubClient) MinimizeOutdatedComment(ctx context.Context, nodeID string) error {
+	variables := map[string]any{"id": nodeID, "classifier": "OUTDATED"}
+	if _, err := graphQL[struct{}](ctx, g, minimizeCommentMutation, variables); err != nil {
+		return fmt.Errorf("failed to minimize comment: %w", err)
+	}
+
+	return nil
+}
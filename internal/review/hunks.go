@@ -0,0 +1,335 @@
+package review
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// maxSnapDistance is how many lines a comment may be moved to land on a line of the diff
+const maxSnapDistance = 3
+
+var hunkHeaderRe = regexp.MustCompile(`^@@ -(\d+)(?:,(\d+))? \+(\d+)(?:,(\d+))? @@`)
+
+// DiffLine is a single line of a unified diff hunk
+type DiffLine struct {
+	Kind    byte // '+' added, '-' deleted, ' ' context
+	OldLine int  // line number in the base version, 0 for added lines
+	NewLine int  // line number in the head version, 0 for deleted lines
+}
+
+// Hunk is a parsed "@@ -a,b +c,d @@" section of a patch
+type Hunk struct {
+	OldStart int
+	OldLines int
+	NewStart int
+	NewLines int
+	Lines    []DiffLine
+}
+
+// FileDiff holds the patch of a single changed file
+type FileDiff struct {
+	Path  string
+	Patch string
+	Hunks []Hunk
+
+	// Context is the surrounding code of the changes at head, see AddContext
+	Context string
+}
+
+// Reasons for not reviewing a changed file
+const (
+	SkipReasonBinary   = "binary file"
+	SkipReasonNoPatch  = "patch missing"
+	SkipReasonTooLarge = "over 500 changes"
+	SkipReasonAIFailed = "AI review unavailable"
+	SkipReasonExcluded = "excluded by configuration"
+)
+
+// SkippedFile is a changed file that was left out of the review
+type SkippedFile struct {
+	Path   string
+	Reason string
+}
+
+// PRDiff is the reviewable diff of a PR: the text sent to the AI, the parsed
+// hunks used to anchor comments and the files that were left out
+type PRDiff struct {
+	Text    string
+	Files   map[string]*FileDiff
+	Skipped []SkippedFile
+
+	order []string // paths of Files in the order GitHub listed them
+}
+
+// FilterPaths moves the files that keep rejects from the diff to its skipped files
+func (d *PRDiff) FilterPaths(keep func(path string) bool) {
+	excluded := false
+	for _, filename := range d.order {
+		if _, ok := d.Files[filename]; ok && !keep(filename) {
+			delete(d.Files, filename)
+			d.Skipped = append(d.Skipped, SkippedFile{Path: filename, Reason: SkipReasonExcluded})
+			excluded = true
+		}
+	}
+	if excluded {
+		d.render()
+	}
+}
+
+// ParsePatch parses the hunks of a unified diff patch as returned by the GitHub API
+func ParsePatch(patch string) ([]Hunk, error) {
+	var hunks []Hunk
+	var current *Hunk
+	oldLine, newLine := 0, 0
+
+	for _, line := range strings.Split(patch, "\n") {
+		if strings.HasPrefix(line, "@@") {
+			m := hunkHeaderRe.FindStringSubmatch(line)
+			if m == nil {
+				return nil, fmt.Errorf("invalid hunk header: %s", line)
+			}
+			hunks = append(hunks, Hunk{
+				OldStart: atoiOr(m[1], 0),
+				OldLines: atoiOr(m[2], 1),
+				NewStart: atoiOr(m[3], 0),
+				NewLines: atoiOr(m[4], 1),
+			})
+			current = &hunks[len(hunks)-1]
+			oldLine, newLine = current.OldStart, current.NewStart
+			continue
+		}
+
+		if current == nil || line == "" {
+			continue
+		}
+
+		switch line[0] {
+		case '+':
+			current.Lines = append(current.Lines, DiffLine{Kind: '+', NewLine: newLine})
+			newLine++
+		case '-':
+			current.Lines = append(current.Lines, DiffLine{Kind: '-', OldLine: oldLine})
+			oldLine++
+		case ' ':
+			current.Lines = append(current.Lines, DiffLine{Kind: ' ', OldLine: oldLine, NewLine: newLine})
+			oldLine++
+			newLine++
+		default:
+			// "\ No newline at end of file" and similar markers
+		}
+	}
+
+	return hunks, nil
+}
+
+// HasLine reports whether a line on the given side ("RIGHT" or "LEFT") is part of the diff
+func (f *FileDiff) HasLine(side string, line int) bool {
+	return f.hunkFor(side, line) != nil
+}
+
+// NearestLine returns the diff line on the given side closest to line, within maxDistance.
+// Changed lines win over context lines at the same distance.
+func (f *FileDiff) NearestLine(side string, line, maxDistance int) (int, bool) {
+	for distance := 0; distance <= maxDistance; distance++ {
+		candidates := []int{line - distance, line + distance}
+		for _, changedOnly := range []bool{true, false} {
+			for _, candidate := range candidates {
+				if candidate < 1 {
+					continue
+				}
+				if dl := f.lineAt(side, candidate); dl != nil && (!changedOnly || dl.Kind != ' ') {
+					return candidate, true
+				}
+			}
+		}
+	}
+	return 0, false
+}
+
+// hunkFor returns the hunk containing a line on the given side
+func (f *FileDiff) hunkFor(side string, line int) *Hunk {
+	for i := range f.Hunks {
+		for _, dl := range f.Hunks[i].Lines {
+			if lineNumber(dl, side) == line {
+				return &f.Hunks[i]
+			}
+		}
+	}
+	return nil
+}
+
+// ValidRange reports whether a multi-line comment from start on startSide to line on side
+// can be posted: both ends must be lines of the same hunk, the start coming first
+func (f *FileDiff) ValidRange(startSide string, start int, side string, line int) bool {
+	for i := range f.Hunks {
+		startIndex, endIndex := -1, -1
+		for j, dl := range f.Hunks[i].Lines {
+			if startIndex == -1 && lineNumber(dl, startSide) == start {
+				startIndex = j
+			}
+			if lineNumber(dl, side) == line {
+				endIndex = j
+			}
+		}
+		if startIndex != -1 || endIndex != -1 {
+			return startIndex != -1 && endIndex != -1 && startIndex < endIndex
+		}
+	}
+	return false
+}
+
+// lineAt returns the diff line with the given number on the given side
+func (f *FileDiff) lineAt(side string, line int) *DiffLine {
+	for i := range f.Hunks {
+		for j, dl := range f.Hunks[i].Lines {
+			if lineNumber(dl, side) == line {
+				return &f.Hunks[i].Lines[j]
+			}
+		}
+	}
+	return nil
+}
+
+// lineNumber returns the number of a diff line on the given side, 0 if it has none
+func lineNumber(dl DiffLine, side string) int {
+	if side == "LEFT" {
+		return dl.OldLine
+	}
+	return dl.NewLine
+}
+
+// findFile resolves a comment path to a file of the diff, tolerating paths the model
+// shortened or prefixed (e.g. "handler.go" or "b/api/handler.go" for "api/handler.go")
+func (d *PRDiff) findFile(path string) *FileDiff {
+	path = strings.TrimPrefix(strings.TrimSpace(path), "./")
+	if f, ok := d.Files[path]; ok {
+		return f
+	}
+
+	var match *FileDiff
+	for name, f := range d.Files {
+		if strings.HasSuffix(name, "/"+path) || strings.HasSuffix(path, "/"+name) {
+			if match != nil {
+				return nil // ambiguous
+			}
+			match = f
+		}
+	}
+	return match
+}
+
+// AnchorComments checks every comment against the diff hunks. Comments on a line of the
+// diff are kept, comments close to one are snapped onto it, and the rest are moved into
+// an "Additional notes" section of the summary instead of failing the whole review. The
+// suggestions of moved comments are kept as plain code.
+func AnchorComments(result ReviewResult, diff *PRDiff) ReviewResult {
+	var anchored, unanchored []ReviewComment
+
+	for _, c := range result.Comments {
+		file := diff.findFile(c.Path)
+		if file == nil {
+			unanchored = append(unanchored, c)
+			continue
+		}
+		c.Path = file.Path
+
+		if c.Side == "" {
+			c.Side = "RIGHT"
+		}
+
+		line, ok := file.NearestLine(c.Side, c.Line, maxSnapDistance)
+		if !ok {
+			unanchored = append(unanchored, c)
+			continue
+		}
+		moved := line != c.Line
+		if c.StartLine > 0 {
+			if c.StartSide == "" {
+				c.StartSide = c.Side
+			}
+			if moved || !file.ValidRange(c.StartSide, c.StartLine, c.Side, line) {
+				// A moved comment or a range GitHub would reject can only cover a single line
+				c.StartLine, c.StartSide = 0, ""
+				moved = true
+			}
+		}
+		c.Line = line
+
+		// A suggestion replaces the lines the comment covers, which aren't the lines it was
+		// written for anymore
+		if moved && c.Suggestion != nil {
+			c = plainSuggestion(c)
+		}
+
+		anchored = append(anchored, c)
+	}
+
+	result.Comments = anchored
+	if len(unanchored) > 0 {
+		// Notes in the summary can't carry a committable suggestion
+		for i, c := range unanchored {
+			if c.Suggestion != nil {
+				unanchored[i] = plainSuggestion(c)
+			}
+		}
+		result.Summary = appendSummarySection(result.Summary, formatAdditionalNotes(unanchored))
+	}
+
+	return result
+}
+
+// AddFilesNotReviewed lists the files left out of the review in the summary
+func AddFilesNotReviewed(result ReviewResult, diff *PRDiff) ReviewResult {
+	if len(diff.Skipped) == 0 {
+		return result
+	}
+
+	var sb strings.Builder
+	sb.WriteString("### 🙈 Files not reviewed\n\n")
+	sb.WriteString("| File | Reason |\n|---|---|\n")
+	for _, skipped := range diff.Skipped {
+		sb.WriteString(fmt.Sprintf("| `%s` | %s |\n", skipped.Path, skipped.Reason))
+	}
+
+	result.Summary = appendSummarySection(result.Summary, sb.String())
+	return result
+}
+
+// formatAdditionalNotes renders comments that couldn't be placed on the diff
+func formatAdditionalNotes(comments []ReviewComment) string {
+	var sb strings.Builder
+	sb.WriteString("### 📝 Additional notes\n\n")
+	sb.WriteString("*These comments refer to lines outside of the diff:*\n")
+	for _, c := range comments {
+		lines := fmt.Sprintf("line %d", c.Line)
+		if c.StartLine > 0 && (c.StartSide == "" || c.StartSide == c.Side) {
+			lines = fmt.Sprintf("lines %d-%d", c.StartLine, c.Line)
+		}
+		if c.Side == "LEFT" {
+			lines = "deleted " + lines
+		}
+		sb.WriteString(fmt.Sprintf("\n**`%s` (%s)**\n\n%s\n", c.Path, lines, c.Body))
+	}
+	return sb.String()
+}
+
+// appendSummarySection adds a section to the review summary, keeping the poem last
+func appendSummarySection(summary, section string) string {
+	if i := strings.Index(summary, poemSeparator); i != -1 {
+		return summary[:i] + "\n\n" + section + summary[i:]
+	}
+	return summary + "\n\n" + section
+}
+
+func atoiOr(s string, fallback int) int {
+	if s == "" {
+		return fallback
+	}
+	n, err := strconv.Atoi(s)
+	if err != nil {
+		return fallback
+	}
+	return n
+}
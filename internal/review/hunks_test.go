@@ -0,0 +1,190 @@
+package review
+
+import (
+	"strings"
+	"testing"
+)
+
+// testPatch changes lines 11-12 and adds line 32 of the new version, deleting old line 11
+const testPatch = `@@ -10,4 +10,5 @@ func main() {
+ 	a := 1
+-	b := 2
++	c := 3
++	d := 4
+ 	e := 5
+ 	f := 6
+@@ -30,2 +31,3 @@ func helper() {
+ 	x := 1
++	y := 2
+ 	z := 3`
+
+func newTestDiff(t *testing.T) *PRDiff {
+	t.Helper()
+	hunks, err := ParsePatch(testPatch)
+	if err != nil {
+		t.Fatalf("ParsePatch: %v", err)
+	}
+	return &PRDiff{Files: map[string]*FileDiff{
+		"cmd/app/main.go": {Path: "cmd/app/main.go", Patch: testPatch, Hunks: hunks},
+	}}
+}
+
+func TestParsePatch(t *testing.T) {
+	hunks, err := ParsePatch(testPatch)
+	if err != nil {
+		t.Fatalf("ParsePatch: %v", err)
+	}
+	if len(hunks) != 2 {
+		t.Fatalf("got %d hunks, want 2", len(hunks))
+	}
+
+	first := hunks[0]
+	if first.OldStart != 10 || first.OldLines != 4 || first.NewStart != 10 || first.NewLines != 5 {
+		t.Errorf("unexpected header of first hunk: %+v", first)
+	}
+	want := []DiffLine{
+		{Kind: ' ', OldLine: 10, NewLine: 10},
+		{Kind: '-', OldLine: 11},
+		{Kind: '+', NewLine: 11},
+		{Kind: '+', NewLine: 12},
+		{Kind: ' ', OldLine: 12, NewLine: 13},
+		{Kind: ' ', OldLine: 13, NewLine: 14},
+	}
+	if len(first.Lines) != len(want) {
+		t.Fatalf("got %d lines, want %d", len(first.Lines), len(want))
+	}
+	for i := range want {
+		if first.Lines[i] != want[i] {
+			t.Errorf("line %d: got %+v, want %+v", i, first.Lines[i], want[i])
+		}
+	}
+
+	if _, err := ParsePatch("@@ broken @@\n+x"); err == nil {
+		t.Errorf("ParsePatch accepted an invalid hunk header")
+	}
+}
+
+func TestNearestLine(t *testing.T) {
+	file := newTestDiff(t).Files["cmd/app/main.go"]
+
+	tests := []struct {
+		side   string
+		line   int
+		want   int
+		wantOK bool
+	}{
+		{"RIGHT", 11, 11, true}, // added line
+		{"RIGHT", 14, 14, true}, // context line
+		{"RIGHT", 16, 14, true}, // snapped back onto the hunk
+		{"RIGHT", 9, 10, true},  // snapped forward onto the hunk
+		{"RIGHT", 20, 0, false}, // too far from any hunk
+		{"LEFT", 11, 11, true},  // deleted line
+		{"RIGHT", 30, 31, true}, // the nearest line, even if unchanged
+		{"LEFT", 25, 0, false},  // outside of the hunks on the old side
+	}
+	for _, tt := range tests {
+		got, ok := file.NearestLine(tt.side, tt.line, maxSnapDistance)
+		if got != tt.want || ok != tt.wantOK {
+			t.Errorf("NearestLine(%s, %d) = %d, %v, want %d, %v", tt.side, tt.line, got, ok, tt.want, tt.wantOK)
+		}
+	}
+}
+
+func TestValidRange(t *testing.T) {
+	file := newTestDiff(t).Files["cmd/app/main.go"]
+
+	tests := []struct {
+		startSide string
+		start     int
+		side      string
+		line      int
+		want      bool
+	}{
+		{"RIGHT", 11, "RIGHT", 13, true},
+		{"LEFT", 11, "RIGHT", 12, true}, // from the deleted line onto the added ones
+		{"RIGHT", 13, "RIGHT", 11, false},
+		{"RIGHT", 12, "RIGHT", 32, false}, // across hunks
+		{"RIGHT", 20, "RIGHT", 21, false}, // outside of the diff
+	}
+	for _, tt := range tests {
+		if got := file.ValidRange(tt.startSide, tt.start, tt.side, tt.line); got != tt.want {
+			t.Errorf("ValidRange(%s %d, %s %d) = %v, want %v", tt.startSide, tt.start, tt.side, tt.line, got, tt.want)
+		}
+	}
+}
+
+func TestAnchorComments(t *testing.T) {
+	result := ReviewResult{
+		Summary: formatSummary("Summary", "_poem_"),
+		Comments: []ReviewComment{
+			{Path: "cmd/app/main.go", Line: 12, Body: "on the diff"},
+			{Path: "main.go", Line: 16, Body: "snapped, shortened path"},
+			{Path: "cmd/app/main.go", Line: 12, StartLine: 11, Body: "range"},
+			{Path: "cmd/app/main.go", Line: 16, StartLine: 15, Body: "moved range"},
+			{Path: "cmd/app/main.go", Line: 11, Side: "LEFT", Body: "deleted line"},
+		},
+	}
+
+	anchored := AnchorComments(result, newTestDiff(t))
+	if len(anchored.Comments) != 5 {
+		t.Fatalf("got %d anchored comments, want 5: %+v", len(anchored.Comments), anchored.Comments)
+	}
+
+	want := []ReviewComment{
+		{Path: "cmd/app/main.go", Line: 12, Side: "RIGHT", Body: "on the diff"},
+		{Path: "cmd/app/main.go", Line: 14, Side: "RIGHT", Body: "snapped, shortened path"},
+		{Path: "cmd/app/main.go", Line: 12, StartLine: 11, StartSide: "RIGHT", Side: "RIGHT", Body: "range"},
+		{Path: "cmd/app/main.go", Line: 14, Side: "RIGHT", Body: "moved range"},
+		{Path: "cmd/app/main.go", Line: 11, Side: "LEFT", Body: "deleted line"},
+	}
+	for i := range want {
+		if anchored.Comments[i] != want[i] {
+			t.Errorf("comment %d: got %+v, want %+v", i, anchored.Comments[i], want[i])
+		}
+	}
+	if strings.Contains(anchored.Summary, "Additional notes") {
+		t.Errorf("summary has additional notes without unanchored comments:\n%s", anchored.Summary)
+	}
+}
+
+func TestAnchorCommentsMovesSuggestionsToPlainCode(t *testing.T) {
+	suggestion := &Suggestion{Original: "\td := 4", Replacement: "\td := 40"}
+	result := ReviewResult{
+		Summary: formatSummary("Summary", "_poem_"),
+		Comments: []ReviewComment{
+			{Path: "cmd/app/main.go", Line: 12, Body: "kept", Suggestion: suggestion},
+			{Path: "cmd/app/main.go", Line: 15, Body: "snapped", Suggestion: suggestion},
+			{Path: "cmd/app/main.go", Line: 50, Body: "far away", Suggestion: suggestion},
+			{Path: "other.go", Line: 1, Body: "unknown file", Suggestion: suggestion},
+		},
+	}
+
+	anchored := AnchorComments(result, newTestDiff(t))
+	if len(anchored.Comments) != 2 {
+		t.Fatalf("got %d anchored comments, want 2", len(anchored.Comments))
+	}
+
+	kept, snapped := anchored.Comments[0], anchored.Comments[1]
+	if kept.Suggestion != suggestion || kept.Body != "kept" {
+		t.Errorf("suggestion of a comment on its line wasn't kept for rendering: %+v", kept)
+	}
+	if snapped.Suggestion != nil || !strings.Contains(snapped.Body, "**Suggested change:**\n```\n\td := 40\n```") {
+		t.Errorf("suggestion of a snapped comment wasn't turned into plain code: %+v", snapped)
+	}
+
+	notes := anchored.Summary[strings.Index(anchored.Summary, "### 📝 Additional notes"):]
+	for _, body := range []string{"far away", "unknown file"} {
+		if !strings.Contains(notes, body) {
+			t.Errorf("additional notes miss %q:\n%s", body, notes)
+		}
+	}
+	if strings.Count(notes, "**Suggested change:**") != 2 {
+		t.Errorf("additional notes dropped suggestions:\n%s", notes)
+	}
+	if strings.Contains(notes, "```suggestion") {
+		t.Errorf("additional notes contain a committable suggestion:\n%s", notes)
+	}
+	if !strings.HasSuffix(anchored.Summary, "_poem_") {
+		t.Errorf("the poem isn't last anymore:\n%s", anchored.Summary)
+	}
+}
@@ -28,19 +28,24 @@ func (ai *AIClient) parseClaudeResponse(claudeText, diff string) ReviewResult {
 		}
 	}
 
-	// Combine summary and poem
-	finalSummary := summary
-	if poem != "" {
-		finalSummary += "\n\n---\n\n**And now, a little poem about your changes 🌪️✨**\n" + poem
+	return ReviewResult{
+		Summary:  formatSummary(summary, poem),
+		Comments: comments,
 	}
+}
 
-	// Add Cyclone branding if not present
-	finalSummary = "## 🌪️ Cyclone AI Code Review\n\n" + finalSummary
+// poemSeparator introduces the poem at the end of a review summary
+const poemSeparator = "\n\n---\n\n**And now, a little poem about your changes 🌪️✨**\n"
 
-	return ReviewResult{
-		Summary:  finalSummary,
-		Comments: comments,
+// formatSummary combines summary and poem under the Cyclone heading
+func formatSummary(summary, poem string) string {
+	finalSummary := summary
+	if poem != "" {
+		finalSummary += poemSeparator + poem
 	}
+
+	// Add Cyclone branding
+	return ReviewHeader + "\n\n" + finalSummary
 }
 
 // extractSection extracts content between $$ delimiters for a given section
@@ -100,17 +105,57 @@ func (ai *AIClient) parsePRCommentBlock(block string) *ReviewComment {
 	lineNumStr := strings.TrimSpace(parts[1])
 	categoryPart := strings.TrimSpace(parts[2])
 
-	lineNum, err := strconv.Atoi(lineNumStr)
+	startLine, lineNum, side, err := parseLineRange(lineNumStr)
 	if err != nil {
 		log.Printf("Invalid line number in PR_COMMENT: %s", lineNumStr)
 		return nil
 	}
 
-	// The categoryPart contains: "emoji **category**:"
+	// The categoryPart contains: "emoji **category**:", optionally followed by a focus area
+	severity, focusArea := ParseSeverity(categoryPart), ParseFocusArea(categoryPart)
+	if severity != "" {
+		// Render the category consistently, whatever emoji or case the model used
+		categoryPart = categoryLabel(severity, focusArea)
+	}
+
 	return &ReviewComment{
-		Path: file,
-		Line: lineNum,
-		Side: "RIGHT",
-		Body: fmt.Sprintf("%s\n\n%s", categoryPart, content),
+		Path:      file,
+		Line:      lineNum,
+		StartLine: startLine,
+		Side:      side,
+		Body:      fmt.Sprintf("%s\n\n%s", categoryPart, content),
+		Severity:  severity,
+		FocusArea: focusArea,
+	}
+}
+
+// parseLineRange parses the line part of a PR_COMMENT header: "45" for a line, "45-52" for
+// a range and a leading minus for lines of the old version, e.g. "-30" or "-30-34"
+func parseLineRange(text string) (startLine, line int, side string, err error) {
+	side = "RIGHT"
+	if strings.HasPrefix(text, "-") {
+		side = "LEFT"
+		text = strings.TrimPrefix(text, "-")
+	}
+
+	first, last, isRange := strings.Cut(text, "-")
+	line, err = strconv.Atoi(strings.TrimSpace(first))
+	if err != nil {
+		return 0, 0, "", err
+	}
+	if !isRange {
+		return 0, line, side, nil
+	}
+
+	end, err := strconv.Atoi(strings.TrimSpace(last))
+	if err != nil {
+		return 0, 0, "", err
+	}
+	if end < line {
+		return 0, 0, "", fmt.Errorf("range %d-%d ends before it starts", line, end)
+	}
+	if end == line {
+		return 0, line, side, nil
 	}
+	return line, end, side, nil
 }
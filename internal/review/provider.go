@@ -0,0 +1,208 @@
+package review
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"math/rand/v2"
+	"sort"
+	"strings"
+	"time"
+
+	"cyclone/internal/config"
+)
+
+// Provider is an LLM backend reviews can be generated with
+type Provider interface {
+	// Name identifies the provider in configuration, e.g. "anthropic"
+	Name() string
+
+	// DefaultModel is used when the repository configuration doesn't name a model
+	DefaultModel() string
+
+	// Complete sends a conversation to the model and returns its reply
+	Complete(ctx context.Context, req CompletionRequest) (*Completion, error)
+}
+
+// Message is a single turn of a conversation with the model
+type Message struct {
+	Role    string `json:"role"` // "user" or "assistant"
+	Content string `json:"content"`
+}
+
+// Tool describes a structured output the model has to produce, with its input as JSON schema
+type Tool struct {
+	Name        string          `json:"name"`
+	Description string          `json:"description"`
+	InputSchema json.RawMessage `json:"input_schema"`
+}
+
+// CompletionRequest is a provider-independent request to a model
+type CompletionRequest struct {
+	Model     string
+	MaxTokens int
+	Messages  []Message
+
+	// Tool, if set, forces the model to answer with the tool's input as structured output
+	Tool *Tool
+}
+
+// Completion is the reply of a model
+type Completion struct {
+	Text      string
+	ToolInput json.RawMessage // set when the request had a Tool
+}
+
+// AIClient handles all AI operations, routing each repository to its configured provider
+type AIClient struct {
+	providers       map[string]Provider
+	defaultProvider string
+	defaultModel    string
+	fallbackModel   string
+	backoff         func(attempt int, err error) time.Duration // wait before a retry, retryDelay
+}
+
+// NewAIClient creates a new AI client. defaultProvider and defaultModel are used for
+// repositories that don't configure their own; an empty defaultModel means the
+// provider's default model. fallbackModel, if set, is tried once the default model
+// keeps failing.
+func NewAIClient(defaultProvider, defaultModel, fallbackModel string, providers ...Provider) (*AIClient, error) {
+	ai := &AIClient{
+		providers:       make(map[string]Provider, len(providers)),
+		defaultProvider: defaultProvider,
+		defaultModel:    defaultModel,
+		fallbackModel:   fallbackModel,
+		backoff:         retryDelay,
+	}
+	for _, p := range providers {
+		ai.providers[p.Name()] = p
+	}
+
+	if _, ok := ai.providers[defaultProvider]; !ok {
+		return nil, fmt.Errorf("default AI provider %q is not configured (available: %s)", defaultProvider, ai.providerNames())
+	}
+
+	return ai, nil
+}
+
+// complete sends a request to the provider and model configured for the repository.
+// Transient failures are retried with exponential backoff; once retries are exhausted
+// the fallback model is tried. If no model replies the error wraps ErrReviewUnavailable.
+// Once ctx is done, the context's error is returned without trying further.
+func (ai *AIClient) complete(ctx context.Context, repoConfig *config.RepositoryConfig, req CompletionRequest) (*Completion, error) {
+	provider, models, err := ai.resolve(repoConfig)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrReviewUnavailable, err)
+	}
+
+	var lastErr error
+	for i, model := range models {
+		if i > 0 {
+			log.Printf("Falling back to model %s after %s failed: %v", model, models[i-1], lastErr)
+		}
+
+		req.Model = model
+		completion, err := ai.completeWithRetry(ctx, provider, req)
+		if err == nil {
+			return completion, nil
+		}
+		if ctx.Err() != nil || !isUnavailable(err) {
+			// Canceled, or the model replied with something unusable - another model won't be asked
+			return nil, err
+		}
+		lastErr = err
+	}
+
+	return nil, fmt.Errorf("%w: %w", ErrReviewUnavailable, lastErr)
+}
+
+// completeWithRetry retries transient provider failures with exponential backoff and
+// jitter, waiting at least as long as the provider's retry-after header asks for. Waiting
+// stops once ctx is done.
+func (ai *AIClient) completeWithRetry(ctx context.Context, provider Provider, req CompletionRequest) (*Completion, error) {
+	var err error
+	for attempt := 0; attempt <= config.MAX_AI_RETRIES; attempt++ {
+		if attempt > 0 {
+			delay := ai.backoff(attempt, err)
+			log.Printf("Retrying %s request (attempt %d of %d) in %s: %v", provider.Name(), attempt, config.MAX_AI_RETRIES, delay.Round(time.Millisecond), err)
+			select {
+			case <-time.After(delay):
+			case <-ctx.Done():
+				return nil, ctx.Err()
+			}
+		}
+
+		var completion *Completion
+		completion, err = provider.Complete(ctx, req)
+		if err == nil {
+			return completion, nil
+		}
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		if !isRetryable(err) {
+			return nil, err
+		}
+	}
+
+	return nil, err
+}
+
+// retryDelay returns the wait before a retry: full jitter over an exponentially growing
+// window, but never less than the provider's retry-after
+func retryDelay(attempt int, err error) time.Duration {
+	window := config.AI_RETRY_BASE_DELAY << (attempt - 1)
+	if window > config.AI_RETRY_MAX_DELAY {
+		window = config.AI_RETRY_MAX_DELAY
+	}
+	delay := window/2 + time.Duration(rand.Int64N(int64(window/2)+1))
+
+	var apiErr *APIError
+	if errors.As(err, &apiErr) && apiErr.RetryAfter > delay {
+		delay = min(apiErr.RetryAfter, config.AI_RETRY_MAX_DELAY)
+	}
+
+	return delay
+}
+
+// resolve picks the provider for a repository and the models to try, in order
+func (ai *AIClient) resolve(repoConfig *config.RepositoryConfig) (Provider, []string, error) {
+	name := ai.defaultProvider
+	model, fallback := ai.defaultModel, ai.fallbackModel
+	if repoConfig.Provider != "" && repoConfig.Provider != name {
+		// Another provider's models can't be used with this one
+		name, model, fallback = repoConfig.Provider, "", ""
+	}
+	if repoConfig.Model != "" {
+		model = repoConfig.Model
+	}
+	if repoConfig.FallbackModel != "" {
+		fallback = repoConfig.FallbackModel
+	}
+
+	provider, ok := ai.providers[name]
+	if !ok {
+		return nil, nil, fmt.Errorf("AI provider %q configured for repository %s is not available (available: %s)", name, repoConfig.Name, ai.providerNames())
+	}
+	if model == "" {
+		model = provider.DefaultModel()
+	}
+
+	models := []string{model}
+	if fallback != "" && fallback != model {
+		models = append(models, fallback)
+	}
+
+	return provider, models, nil
+}
+
+func (ai *AIClient) providerNames() string {
+	names := make([]string, 0, len(ai.providers))
+	for name := range ai.providers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return strings.Join(names, ", ")
+}
@@ -0,0 +1,112 @@
+package review
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+// AnthropicProvider generates reviews with Claude through the Anthropic Messages API
+type AnthropicProvider struct {
+	apiKey string
+	model  string
+	client *http.Client
+}
+
+// ClaudeResponse represents the response from Claude API
+type ClaudeResponse struct {
+	Content []struct {
+		Type  string          `json:"type"` // "text" or "tool_use"
+		Text  string          `json:"text"`
+		Name  string          `json:"name"`
+		Input json.RawMessage `json:"input"`
+	} `json:"content"`
+}
+
+// ClaudeToolChoice forces Claude to use a specific tool
+type ClaudeToolChoice struct {
+	Type string `json:"type"`
+	Name string `json:"name,omitempty"`
+}
+
+// ClaudeRequest represents a request to Claude API
+type ClaudeRequest struct {
+	Model      string            `json:"model"`
+	MaxTokens  int               `json:"max_tokens"`
+	Messages   []Message         `json:"messages"`
+	Tools      []Tool            `json:"tools,omitempty"`
+	ToolChoice *ClaudeToolChoice `json:"tool_choice,omitempty"`
+}
+
+// NewAnthropicProvider creates a Claude provider with the provided API key and default model
+func NewAnthropicProvider(apiKey, model string) *AnthropicProvider {
+	return &AnthropicProvider{
+		apiKey: apiKey,
+		model:  model, // e.g. claude-sonnet-4-20250514, claude-3-5-sonnet-20241022, claude-3-haiku-20240307
+		client: &http.Client{Timeout: 60 * time.Second},
+	}
+}
+
+func (p *AnthropicProvider) Name() string         { return "anthropic" }
+func (p *AnthropicProvider) DefaultModel() string { return p.model }
+
+// Complete sends the conversation to Claude API, forcing a tool call if a Tool is requested
+func (p *AnthropicProvider) Complete(ctx context.Context, req CompletionRequest) (*Completion, error) {
+	reqBody := ClaudeRequest{
+		Model:     req.Model,
+		MaxTokens: req.MaxTokens,
+		Messages:  req.Messages,
+	}
+	if req.Tool != nil {
+		reqBody.Tools = []Tool{*req.Tool}
+		reqBody.ToolChoice = &ClaudeToolChoice{Type: "tool", Name: req.Tool.Name}
+	}
+
+	jsonData, err := json.Marshal(reqBody)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal request: %w", err)
+	}
+
+	httpReq, err := http.NewRequestWithContext(ctx, "POST", "https://api.anthropic.com/v1/messages", bytes.NewBuffer(jsonData))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+
+	httpReq.Header.Set("Content-Type", "application/json")
+	httpReq.Header.Set("x-api-key", p.apiKey)
+	httpReq.Header.Set("anthropic-version", "2023-06-01")
+
+	resp, err := p.client.Do(httpReq)
+	if err != nil {
+		return nil, fmt.Errorf("failed to call Claude API: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, newAPIError("Claude", resp)
+	}
+
+	var claudeResp ClaudeResponse
+	if err := json.NewDecoder(resp.Body).Decode(&claudeResp); err != nil {
+		return nil, fmt.Errorf("failed to decode response: %w", err)
+	}
+
+	completion := &Completion{}
+	for _, block := range claudeResp.Content {
+		switch {
+		case block.Type == "tool_use" && req.Tool != nil && block.Name == req.Tool.Name:
+			completion.ToolInput = block.Input
+		case block.Type == "text" && completion.Text == "":
+			completion.Text = block.Text
+		}
+	}
+
+	if req.Tool != nil && completion.ToolInput == nil {
+		return nil, fmt.Errorf("response contains no %s tool call", req.Tool.Name)
+	}
+
+	return completion, nil
+}
@@ -0,0 +1,60 @@
+package review
+
+import (
+	"context"
+	"fmt"
+	"sync"
+)
+
+// FakeProvider is a Provider returning canned completions, for tests and local runs
+// without an LLM. It records every request it receives.
+type FakeProvider struct {
+	mu          sync.Mutex
+	completions []*Completion
+	errs        []error
+	requests    []CompletionRequest
+}
+
+// NewFakeProvider creates a fake provider that replies with the given completions in order
+func NewFakeProvider(completions ...*Completion) *FakeProvider {
+	return &FakeProvider{completions: completions}
+}
+
+func (p *FakeProvider) Name() string         { return "fake" }
+func (p *FakeProvider) DefaultModel() string { return "fake-model" }
+
+// FailNext makes the next call to Complete return err instead of a completion
+func (p *FakeProvider) FailNext(err error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.errs = append(p.errs, err)
+}
+
+// Complete records the request and returns the next queued error or completion
+func (p *FakeProvider) Complete(ctx context.Context, req CompletionRequest) (*Completion, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.requests = append(p.requests, req)
+
+	if len(p.errs) > 0 {
+		err := p.errs[0]
+		p.errs = p.errs[1:]
+		return nil, err
+	}
+
+	if len(p.completions) == 0 {
+		return nil, fmt.Errorf("fake provider has no completions left")
+	}
+	completion := p.completions[0]
+	p.completions = p.completions[1:]
+
+	return completion, nil
+}
+
+// Requests returns the requests the provider has received so far
+func (p *FakeProvider) Requests() []CompletionRequest {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return append([]CompletionRequest(nil), p.requests...)
+}
@@ -0,0 +1,96 @@
+package review
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// OllamaProvider generates reviews with a local model served by Ollama
+type OllamaProvider struct {
+	baseURL string
+	model   string
+	client  *http.Client
+}
+
+type ollamaRequest struct {
+	Model    string          `json:"model"`
+	Messages []Message       `json:"messages"`
+	Stream   bool            `json:"stream"`
+	Format   json.RawMessage `json:"format,omitempty"`
+	Options  struct {
+		NumPredict int `json:"num_predict,omitempty"`
+	} `json:"options"`
+}
+
+type ollamaResponse struct {
+	Message struct {
+		Content string `json:"content"`
+	} `json:"message"`
+}
+
+// NewOllamaProvider creates a provider for the Ollama server at baseURL, e.g. http://localhost:11434
+func NewOllamaProvider(baseURL, model string) *OllamaProvider {
+	return &OllamaProvider{
+		baseURL: strings.TrimRight(baseURL, "/"),
+		model:   model,
+		// Local models are considerably slower than hosted ones
+		client: &http.Client{Timeout: 10 * time.Minute},
+	}
+}
+
+func (p *OllamaProvider) Name() string         { return "ollama" }
+func (p *OllamaProvider) DefaultModel() string { return p.model }
+
+// Complete sends the conversation to Ollama's chat API. A requested Tool is mapped onto
+// Ollama's structured outputs, constraining the reply to the tool's JSON schema.
+func (p *OllamaProvider) Complete(ctx context.Context, req CompletionRequest) (*Completion, error) {
+	reqBody := ollamaRequest{
+		Model:    req.Model,
+		Messages: req.Messages,
+	}
+	reqBody.Options.NumPredict = req.MaxTokens
+	if req.Tool != nil {
+		reqBody.Format = req.Tool.InputSchema
+	}
+
+	jsonData, err := json.Marshal(reqBody)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal request: %w", err)
+	}
+
+	httpReq, err := http.NewRequestWithContext(ctx, "POST", p.baseURL+"/api/chat", bytes.NewBuffer(jsonData))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	httpReq.Header.Set("Content-Type", "application/json")
+
+	resp, err := p.client.Do(httpReq)
+	if err != nil {
+		return nil, fmt.Errorf("failed to call Ollama API: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, newAPIError("Ollama", resp)
+	}
+
+	var ollamaResp ollamaResponse
+	if err := json.NewDecoder(resp.Body).Decode(&ollamaResp); err != nil {
+		return nil, fmt.Errorf("failed to decode response: %w", err)
+	}
+
+	completion := &Completion{Text: ollamaResp.Message.Content}
+	if req.Tool != nil {
+		if !json.Valid([]byte(ollamaResp.Message.Content)) {
+			return nil, fmt.Errorf("structured output is not valid JSON")
+		}
+		completion.ToolInput = json.RawMessage(ollamaResp.Message.Content)
+	}
+
+	return completion, nil
+}
@@ -0,0 +1,148 @@
+package review
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// OpenAIProvider generates reviews through an OpenAI-compatible chat completions API,
+// e.g. OpenAI itself or self-hosted servers such as vLLM
+type OpenAIProvider struct {
+	baseURL string
+	apiKey  string
+	model   string
+	client  *http.Client
+}
+
+type openAITool struct {
+	Type     string         `json:"type"`
+	Function openAIFunction `json:"function"`
+}
+
+type openAIFunction struct {
+	Name        string          `json:"name"`
+	Description string          `json:"description,omitempty"`
+	Parameters  json.RawMessage `json:"parameters,omitempty"`
+}
+
+type openAIToolChoice struct {
+	Type     string `json:"type"`
+	Function struct {
+		Name string `json:"name"`
+	} `json:"function"`
+}
+
+type openAIRequest struct {
+	Model      string            `json:"model"`
+	MaxTokens  int               `json:"max_tokens"`
+	Messages   []Message         `json:"messages"`
+	Tools      []openAITool      `json:"tools,omitempty"`
+	ToolChoice *openAIToolChoice `json:"tool_choice,omitempty"`
+}
+
+type openAIResponse struct {
+	Choices []struct {
+		Message struct {
+			Content   string `json:"content"`
+			ToolCalls []struct {
+				Function struct {
+					Name      string `json:"name"`
+					Arguments string `json:"arguments"`
+				} `json:"function"`
+			} `json:"tool_calls"`
+		} `json:"message"`
+	} `json:"choices"`
+}
+
+// NewOpenAIProvider creates a provider for an OpenAI-compatible API. baseURL defaults to
+// https://api.openai.com/v1; apiKey may be empty for self-hosted servers.
+func NewOpenAIProvider(baseURL, apiKey, model string) *OpenAIProvider {
+	if baseURL == "" {
+		baseURL = "https://api.openai.com/v1"
+	}
+	return &OpenAIProvider{
+		baseURL: strings.TrimRight(baseURL, "/"),
+		apiKey:  apiKey,
+		model:   model,
+		client:  &http.Client{Timeout: 120 * time.Second},
+	}
+}
+
+func (p *OpenAIProvider) Name() string         { return "openai" }
+func (p *OpenAIProvider) DefaultModel() string { return p.model }
+
+// Complete sends the conversation as a chat completion, forcing a function call if a Tool is requested
+func (p *OpenAIProvider) Complete(ctx context.Context, req CompletionRequest) (*Completion, error) {
+	reqBody := openAIRequest{
+		Model:     req.Model,
+		MaxTokens: req.MaxTokens,
+		Messages:  req.Messages,
+	}
+	if req.Tool != nil {
+		reqBody.Tools = []openAITool{{
+			Type: "function",
+			Function: openAIFunction{
+				Name:        req.Tool.Name,
+				Description: req.Tool.Description,
+				Parameters:  req.Tool.InputSchema,
+			},
+		}}
+		reqBody.ToolChoice = &openAIToolChoice{Type: "function"}
+		reqBody.ToolChoice.Function.Name = req.Tool.Name
+	}
+
+	jsonData, err := json.Marshal(reqBody)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal request: %w", err)
+	}
+
+	httpReq, err := http.NewRequestWithContext(ctx, "POST", p.baseURL+"/chat/completions", bytes.NewBuffer(jsonData))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+
+	httpReq.Header.Set("Content-Type", "application/json")
+	if p.apiKey != "" {
+		httpReq.Header.Set("Authorization", "Bearer "+p.apiKey)
+	}
+
+	resp, err := p.client.Do(httpReq)
+	if err != nil {
+		return nil, fmt.Errorf("failed to call OpenAI-compatible API: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, newAPIError("OpenAI-compatible", resp)
+	}
+
+	var openAIResp openAIResponse
+	if err := json.NewDecoder(resp.Body).Decode(&openAIResp); err != nil {
+		return nil, fmt.Errorf("failed to decode response: %w", err)
+	}
+
+	if len(openAIResp.Choices) == 0 {
+		return nil, fmt.Errorf("response contains no choices")
+	}
+
+	message := openAIResp.Choices[0].Message
+	completion := &Completion{Text: message.Content}
+	if req.Tool != nil {
+		for _, call := range message.ToolCalls {
+			if call.Function.Name == req.Tool.Name {
+				completion.ToolInput = json.RawMessage(call.Function.Arguments)
+				break
+			}
+		}
+		if completion.ToolInput == nil {
+			return nil, fmt.Errorf("response contains no %s function call", req.Tool.Name)
+		}
+	}
+
+	return completion, nil
+}
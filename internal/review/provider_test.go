@@ -0,0 +1,272 @@
+package review
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+
+	"cyclone/internal/config"
+)
+
+// namedProvider is a FakeProvider registered under another name, to test provider selection
+type namedProvider struct {
+	*FakeProvider
+	name string
+}
+
+func (p namedProvider) Name() string { return p.name }
+
+// newTestClient creates an AI client for the providers that retries without waiting
+func newTestClient(t *testing.T, defaultProvider, defaultModel, fallbackModel string, providers ...Provider) *AIClient {
+	t.Helper()
+	ai, err := NewAIClient(defaultProvider, defaultModel, fallbackModel, providers...)
+	if err != nil {
+		t.Fatalf("NewAIClient: %v", err)
+	}
+	ai.backoff = func(int, error) time.Duration { return 0 }
+	return ai
+}
+
+var testRepoConfig = &config.RepositoryConfig{Name: "repo", Precision: config.PrecisionMedium}
+
+func TestCompleteRetriesTransientErrors(t *testing.T) {
+	for _, status := range []int{http.StatusTooManyRequests, http.StatusInternalServerError, http.StatusBadGateway, 529} {
+		fake := NewFakeProvider(&Completion{Text: "ok"})
+		fake.FailNext(&APIError{Provider: "fake", StatusCode: status})
+		fake.FailNext(&APIError{Provider: "fake", StatusCode: status})
+		ai := newTestClient(t, "fake", "", "", fake)
+
+		completion, err := ai.complete(context.Background(), testRepoConfig, CompletionRequest{})
+		if err != nil {
+			t.Fatalf("status %d: complete: %v", status, err)
+		}
+		if completion.Text != "ok" {
+			t.Errorf("status %d: got %q, want %q", status, completion.Text, "ok")
+		}
+		if got := len(fake.Requests()); got != 3 {
+			t.Errorf("status %d: got %d requests, want 3", status, got)
+		}
+	}
+}
+
+func TestCompleteDoesNotRetryClientErrors(t *testing.T) {
+	fake := NewFakeProvider(&Completion{Text: "ok"})
+	fake.FailNext(&APIError{Provider: "fake", StatusCode: http.StatusBadRequest})
+	ai := newTestClient(t, "fake", "", "fallback-model", fake)
+
+	_, err := ai.complete(context.Background(), testRepoConfig, CompletionRequest{})
+	var apiErr *APIError
+	if !errors.As(err, &apiErr) || apiErr.StatusCode != http.StatusBadRequest {
+		t.Fatalf("got error %v, want the 400 API error", err)
+	}
+	if errors.Is(err, ErrReviewUnavailable) {
+		t.Errorf("a rejected request is not ErrReviewUnavailable: %v", err)
+	}
+	if got := len(fake.Requests()); got != 1 {
+		t.Errorf("got %d requests, want 1 (no retry, no fallback)", got)
+	}
+}
+
+func TestCompleteFallsBackToFallbackModel(t *testing.T) {
+	fake := NewFakeProvider(&Completion{Text: "from fallback"})
+	for i := 0; i <= config.MAX_AI_RETRIES; i++ {
+		fake.FailNext(&APIError{Provider: "fake", StatusCode: http.StatusServiceUnavailable})
+	}
+	ai := newTestClient(t, "fake", "primary-model", "fallback-model", fake)
+
+	completion, err := ai.complete(context.Background(), testRepoConfig, CompletionRequest{})
+	if err != nil {
+		t.Fatalf("complete: %v", err)
+	}
+	if completion.Text != "from fallback" {
+		t.Errorf("got %q, want %q", completion.Text, "from fallback")
+	}
+
+	requests := fake.Requests()
+	if len(requests) != config.MAX_AI_RETRIES+2 {
+		t.Fatalf("got %d requests, want %d", len(requests), config.MAX_AI_RETRIES+2)
+	}
+	for _, req := range requests[:len(requests)-1] {
+		if req.Model != "primary-model" {
+			t.Errorf("retry sent to %q, want primary-model", req.Model)
+		}
+	}
+	if last := requests[len(requests)-1]; last.Model != "fallback-model" {
+		t.Errorf("fallback sent to %q, want fallback-model", last.Model)
+	}
+}
+
+func TestCompleteFallsBackOnUnknownModel(t *testing.T) {
+	fake := NewFakeProvider(&Completion{Text: "from fallback"})
+	fake.FailNext(&APIError{Provider: "fake", StatusCode: http.StatusNotFound})
+	ai := newTestClient(t, "fake", "retired-model", "fallback-model", fake)
+
+	if _, err := ai.complete(context.Background(), testRepoConfig, CompletionRequest{}); err != nil {
+		t.Fatalf("complete: %v", err)
+	}
+	if got := len(fake.Requests()); got != 2 {
+		t.Errorf("got %d requests, want 2 (no retry of the unknown model)", got)
+	}
+}
+
+func TestCompleteUnavailable(t *testing.T) {
+	fake := NewFakeProvider()
+	for i := 0; i < 2*(config.MAX_AI_RETRIES+1); i++ {
+		fake.FailNext(&APIError{Provider: "fake", StatusCode: http.StatusInternalServerError})
+	}
+	ai := newTestClient(t, "fake", "primary-model", "fallback-model", fake)
+
+	_, err := ai.complete(context.Background(), testRepoConfig, CompletionRequest{})
+	if !errors.Is(err, ErrReviewUnavailable) {
+		t.Fatalf("got error %v, want ErrReviewUnavailable", err)
+	}
+}
+
+func TestCompleteStopsWaitingWhenCanceled(t *testing.T) {
+	fake := NewFakeProvider(&Completion{Text: "ok"})
+	fake.FailNext(&APIError{Provider: "fake", StatusCode: http.StatusTooManyRequests})
+	ai := newTestClient(t, "fake", "", "fallback-model", fake)
+	ai.backoff = func(int, error) time.Duration { return time.Hour }
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+
+	_, err := ai.complete(ctx, testRepoConfig, CompletionRequest{})
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("got error %v, want context.DeadlineExceeded", err)
+	}
+	if got := len(fake.Requests()); got != 1 {
+		t.Errorf("got %d requests, want 1 (no fallback once canceled)", got)
+	}
+}
+
+func TestGenerateReviewParsesToolInput(t *testing.T) {
+	input := json.RawMessage(`{
+  "summary": "Looks good overall.",
+  "poem": "_Small and neat_",
+  "comments": [
+    {"path": "main.go", "line": 12, "severity": "issue", "focus_area": "security", "body": "Validate the input."},
+    {"path": "main.go", "line": 20, "start_line": 18, "severity": "nit", "body": "Rename this."}
+  ]
+}`)
+	fake := NewFakeProvider(&Completion{ToolInput: input})
+	ai := newTestClient(t, "fake", "", "", fake)
+
+	result, err := ai.GenerateReview(context.Background(), &PRDiff{Text: "diff"}, "Title", "Body", testRepoConfig)
+	if err != nil {
+		t.Fatalf("GenerateReview: %v", err)
+	}
+
+	if want := formatSummary("Looks good overall.", "_Small and neat_"); result.Summary != want {
+		t.Errorf("got summary %q, want %q", result.Summary, want)
+	}
+	if len(result.Comments) != 2 {
+		t.Fatalf("got %d comments, want 2", len(result.Comments))
+	}
+	first, second := result.Comments[0], result.Comments[1]
+	if first.Path != "main.go" || first.Line != 12 || first.Side != "RIGHT" || first.Severity != SeverityIssue || first.FocusArea != FocusSecurity {
+		t.Errorf("unexpected first comment: %+v", first)
+	}
+	if second.StartLine != 18 || second.Line != 20 || second.Severity != SeverityNit {
+		t.Errorf("unexpected second comment: %+v", second)
+	}
+
+	requests := fake.Requests()
+	if len(requests) != 1 || requests[0].Tool == nil || requests[0].Tool.Name != submitReviewTool.Name {
+		t.Errorf("review wasn't requested as a %s tool call: %+v", submitReviewTool.Name, requests)
+	}
+}
+
+func TestGenerateReviewFallsBackToTextFormat(t *testing.T) {
+	text := "SUMMARY: $$\nFine.\n$$\n\nPOEM: $$\n_ok_\n$$\n\nPR_COMMENT:main.go:3: ⚠️ **issue**: $$\nCheck the error.\n$$"
+	fake := NewFakeProvider(&Completion{Text: text})
+	fake.FailNext(&APIError{Provider: "fake", StatusCode: http.StatusUnprocessableEntity, Message: "invalid tool schema"})
+	ai := newTestClient(t, "fake", "", "fallback-model", fake)
+
+	result, err := ai.GenerateReview(context.Background(), &PRDiff{Text: "diff"}, "Title", "Body", testRepoConfig)
+	if err != nil {
+		t.Fatalf("GenerateReview: %v", err)
+	}
+	if len(result.Comments) != 1 || result.Comments[0].Line != 3 {
+		t.Errorf("unexpected comments: %+v", result.Comments)
+	}
+
+	requests := fake.Requests()
+	if len(requests) != 2 {
+		t.Fatalf("got %d requests, want 2", len(requests))
+	}
+	if requests[1].Tool != nil || requests[1].Model != fake.DefaultModel() {
+		t.Errorf("text format not requested from the same model: %+v", requests[1])
+	}
+}
+
+func TestRepositoryProviderSelection(t *testing.T) {
+	defaultFake := NewFakeProvider(&Completion{Text: "default"})
+	local := namedProvider{FakeProvider: NewFakeProvider(&Completion{Text: "local"}), name: "ollama"}
+	ai := newTestClient(t, "fake", "default-model", "default-fallback", defaultFake, local)
+
+	repoConfig := &config.RepositoryConfig{Name: "sensitive", Provider: "ollama", Model: "qwen"}
+	answer, err := ai.sendMessages(context.Background(), repoConfig, []Message{{Role: "user", Content: "hi"}}, 100)
+	if err != nil {
+		t.Fatalf("sendMessages: %v", err)
+	}
+	if answer != "local" {
+		t.Errorf("got %q from the wrong provider", answer)
+	}
+	if requests := local.Requests(); len(requests) != 1 || requests[0].Model != "qwen" {
+		t.Errorf("unexpected requests to the repository's provider: %+v", requests)
+	}
+	if requests := defaultFake.Requests(); len(requests) != 0 {
+		t.Errorf("default provider was asked: %+v", requests)
+	}
+}
+
+func TestRepositoryModelSelection(t *testing.T) {
+	tests := []struct {
+		name       string
+		repoConfig *config.RepositoryConfig
+		wantModels []string
+	}{
+		{"defaults", &config.RepositoryConfig{}, []string{"default-model", "default-fallback"}},
+		{"repository model", &config.RepositoryConfig{Model: "big"}, []string{"big", "default-fallback"}},
+		{"repository fallback", &config.RepositoryConfig{Model: "big", FallbackModel: "small"}, []string{"big", "small"}},
+		{"same provider by name", &config.RepositoryConfig{Provider: "fake"}, []string{"default-model", "default-fallback"}},
+		{"other provider drops default models", &config.RepositoryConfig{Provider: "ollama"}, []string{"fake-model"}},
+	}
+
+	local := namedProvider{FakeProvider: NewFakeProvider(), name: "ollama"}
+	ai := newTestClient(t, "fake", "default-model", "default-fallback", NewFakeProvider(), local)
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, models, err := ai.resolve(tt.repoConfig)
+			if err != nil {
+				t.Fatalf("resolve: %v", err)
+			}
+			if len(models) != len(tt.wantModels) {
+				t.Fatalf("got models %v, want %v", models, tt.wantModels)
+			}
+			for i := range models {
+				if models[i] != tt.wantModels[i] {
+					t.Fatalf("got models %v, want %v", models, tt.wantModels)
+				}
+			}
+		})
+	}
+}
+
+func TestUnknownRepositoryProvider(t *testing.T) {
+	fake := NewFakeProvider()
+	ai := newTestClient(t, "fake", "", "", fake)
+
+	_, err := ai.complete(context.Background(), &config.RepositoryConfig{Name: "repo", Provider: "missing"}, CompletionRequest{})
+	if !errors.Is(err, ErrReviewUnavailable) {
+		t.Fatalf("got error %v, want ErrReviewUnavailable", err)
+	}
+	if got := len(fake.Requests()); got != 0 {
+		t.Errorf("got %d requests to the default provider, want 0", got)
+	}
+}
@@ -0,0 +1,186 @@
+package review
+
+import (
+	"log"
+	"strings"
+
+	"cyclone/internal/config"
+)
+
+// Severity is how important a review comment is
+type Severity string
+
+const (
+	SeverityNit        Severity = "nit"
+	SeveritySuggestion Severity = "suggestion"
+	SeverityQuestion   Severity = "question"
+	SeverityIssue      Severity = "issue"
+	SeverityBlocking   Severity = "blocking"
+)
+
+// FocusArea is the aspect of the code a review comment is about
+type FocusArea string
+
+const (
+	FocusStyle    FocusArea = "style"
+	FocusPerf     FocusArea = "perf"
+	FocusSecurity FocusArea = "security"
+	FocusDocs     FocusArea = "docs"
+	FocusTest     FocusArea = "test"
+	FocusRefactor FocusArea = "refactor"
+)
+
+// Review events, decided from the severities of a review's comments
+const (
+	ReviewEventComment        = "COMMENT"
+	ReviewEventApprove        = "APPROVE"
+	ReviewEventRequestChanges = "REQUEST_CHANGES"
+)
+
+// severityRanks orders severities for thresholds; questions rank with suggestions
+var severityRanks = map[Severity]int{
+	SeverityNit:        1,
+	SeveritySuggestion: 2,
+	SeverityQuestion:   2,
+	SeverityIssue:      3,
+	SeverityBlocking:   4,
+}
+
+// severityPrefixes and focusAreaPrefixes render categories the same way the text protocol does
+var severityPrefixes = map[Severity]string{
+	SeverityNit:        "🧰 **nit**",
+	SeveritySuggestion: "💡 **suggestion**",
+	SeverityIssue:      "⚠️ **issue**",
+	SeverityBlocking:   "🚫 **blocking**",
+	SeverityQuestion:   "❓ **question**",
+}
+
+var focusAreaPrefixes = map[FocusArea]string{
+	FocusStyle:    "🎨 **style**",
+	FocusPerf:     "⚡ **perf**",
+	FocusSecurity: "🔒 **security**",
+	FocusDocs:     "📚 **docs**",
+	FocusTest:     "🧪 **test**",
+	FocusRefactor: "🔧 **refactor**",
+}
+
+// severityAliases maps the names and emoji models use for severities, lowercased
+var severityAliases = map[string]Severity{
+	"nit": SeverityNit, "nitpick": SeverityNit, "🧰": SeverityNit, "🔍": SeverityNit,
+	"suggestion": SeveritySuggestion, "suggest": SeveritySuggestion, "💡": SeveritySuggestion,
+	"question": SeverityQuestion, "❓": SeverityQuestion, "❔": SeverityQuestion,
+	"issue": SeverityIssue, "warning": SeverityIssue, "⚠️": SeverityIssue, "⚠": SeverityIssue,
+	"blocking": SeverityBlocking, "blocker": SeverityBlocking, "critical": SeverityBlocking, "🚫": SeverityBlocking,
+}
+
+// focusAreaAliases maps the names and emoji models use for focus areas, lowercased
+var focusAreaAliases = map[string]FocusArea{
+	"style": FocusStyle, "🎨": FocusStyle,
+	"perf": FocusPerf, "performance": FocusPerf, "⚡": FocusPerf,
+	"security": FocusSecurity, "🔒": FocusSecurity,
+	"docs": FocusDocs, "doc": FocusDocs, "documentation": FocusDocs, "📚": FocusDocs,
+	"test": FocusTest, "tests": FocusTest, "testing": FocusTest, "🧪": FocusTest,
+	"refactor": FocusRefactor, "refactoring": FocusRefactor, "🔧": FocusRefactor,
+}
+
+// categoryTokens splits a category label like "🚫 **Blocking**:" into lowercased words and emoji
+func categoryTokens(text string) []string {
+	text = strings.ToLower(text)
+	text = strings.NewReplacer("*", " ", ":", " ", "[", " ", "]", " ", "_", " ").Replace(text)
+
+	var tokens []string
+	for _, field := range strings.Fields(text) {
+		tokens = append(tokens, field)
+		// Emoji glued to a word, e.g. "🚫blocking"
+		if trimmed := strings.TrimLeftFunc(field, isEmojiRune); trimmed != field && trimmed != "" {
+			tokens = append(tokens, strings.TrimSuffix(field, trimmed), trimmed)
+		}
+	}
+	return tokens
+}
+
+func isEmojiRune(r rune) bool {
+	return r > 0x2000
+}
+
+// ParseSeverity leniently parses a severity from its name or emoji, in any case, and
+// returns "" if text names none
+func ParseSeverity(text string) Severity {
+	for _, token := range categoryTokens(text) {
+		if severity, ok := severityAliases[token]; ok {
+			return severity
+		}
+	}
+	return ""
+}
+
+// ParseFocusArea leniently parses a focus area from its name or emoji, in any case, and
+// returns "" if text names none
+func ParseFocusArea(text string) FocusArea {
+	for _, token := range categoryTokens(text) {
+		if focusArea, ok := focusAreaAliases[token]; ok {
+			return focusArea
+		}
+	}
+	return ""
+}
+
+// categoryLabel renders the category prefix of a comment body, e.g. "🚫 **blocking**: 🔒 **security**:"
+func categoryLabel(severity Severity, focusArea FocusArea) string {
+	label := severityPrefixes[severity] + ":"
+	if prefix, ok := focusAreaPrefixes[focusArea]; ok {
+		label += " " + prefix + ":"
+	}
+	return label
+}
+
+// FilterBySeverity drops comments below the minimum severity. Comments without a
+// severity are kept, and an empty minimum keeps everything.
+func FilterBySeverity(result ReviewResult, minSeverity Severity) ReviewResult {
+	minRank, ok := severityRanks[minSeverity]
+	if !ok {
+		return result
+	}
+
+	var kept []ReviewComment
+	for _, comment := range result.Comments {
+		if rank, ok := severityRanks[comment.Severity]; ok && rank < minRank {
+			continue
+		}
+		kept = append(kept, comment)
+	}
+
+	if dropped := len(result.Comments) - len(kept); dropped > 0 {
+		log.Printf("Dropped %d comment(s) below severity %s", dropped, minSeverity)
+	}
+
+	result.Comments = kept
+	return result
+}
+
+// ReviewEvent decides the event of a review according to the repository's review policy:
+// changes are requested on the severities the policy names, the PR is approved when
+// nothing above a nit was found and approving is enabled, otherwise Cyclone comments.
+func ReviewEvent(result ReviewResult, policy config.ReviewPolicy, approveClean bool) string {
+	clean := true
+	for _, comment := range result.Comments {
+		switch comment.Severity {
+		case SeverityBlocking:
+			if policy != config.ReviewPolicyNever {
+				return ReviewEventRequestChanges
+			}
+		case SeverityIssue:
+			if policy == config.ReviewPolicyIssue {
+				return ReviewEventRequestChanges
+			}
+		case SeverityNit:
+			continue
+		}
+		clean = false
+	}
+
+	if clean && approveClean {
+		return ReviewEventApprove
+	}
+	return ReviewEventComment
+}
@@ -0,0 +1,37 @@
+package review
+
+import (
+	"strings"
+	"unicode"
+)
+
+// textSimilarity returns the Jaccard similarity of the word sets of two texts, from 0
+// (nothing in common) to 1 (same words). Markdown, emoji and case are ignored.
+func textSimilarity(a, b string) float64 {
+	wordsA, wordsB := wordSet(a), wordSet(b)
+	if len(wordsA) == 0 && len(wordsB) == 0 {
+		return 1
+	}
+
+	common := 0
+	for w := range wordsA {
+		if wordsB[w] {
+			common++
+		}
+	}
+
+	return float64(common) / float64(len(wordsA)+len(wordsB)-common)
+}
+
+// wordSet splits text into its distinct lower-case words
+func wordSet(text string) map[string]bool {
+	words := strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r) && r != '_'
+	})
+
+	set := make(map[string]bool, len(words))
+	for _, w := range words {
+		set[w] = true
+	}
+	return set
+}
@@ -0,0 +1,177 @@
+package review
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// StructuredReview is the typed review Claude submits through the submit_review tool
+type StructuredReview struct {
+	Summary  string              `json:"summary"`
+	Poem     string              `json:"poem"`
+	Comments []StructuredComment `json:"comments"`
+}
+
+// StructuredComment is a single line-specific comment of a StructuredReview
+type StructuredComment struct {
+	Path      string `json:"path"`
+	Line      int    `json:"line"`
+	StartLine int    `json:"start_line,omitempty"`
+	StartSide string `json:"start_side,omitempty"`
+	Side      string `json:"side,omitempty"`
+	Severity  string `json:"severity"`
+	FocusArea string `json:"focus_area,omitempty"`
+	Body      string `json:"body"`
+
+	// Suggestion replaces lines start_line..line with a one-click fix
+	Suggestion *StructuredSuggestion `json:"suggestion,omitempty"`
+}
+
+// StructuredSuggestion is the replacement code of a StructuredComment
+type StructuredSuggestion struct {
+	Original    string `json:"original"`
+	Replacement string `json:"replacement"`
+}
+
+// submitReviewTool is the tool Claude calls to submit a structured review
+var submitReviewTool = Tool{
+	Name:        "submit_review",
+	Description: "Submit the code review for the pull request: an overall summary, a short poem and line-specific comments.",
+	InputSchema: json.RawMessage(`{
+  "type": "object",
+  "properties": {
+    "summary": {"type": "string", "description": "Overall review summary in GitHub markdown"},
+    "poem": {"type": "string", "description": "A short, lighthearted poem (2-4 lines) in italic"},
+    "comments": {
+      "type": "array",
+      "items": {
+        "type": "object",
+        "properties": {
+          "path": {"type": "string", "description": "File path exactly as shown in the diff"},
+          "line": {"type": "integer", "minimum": 1, "description": "Line number the comment refers to"},
+          "start_line": {"type": "integer", "minimum": 1, "description": "First line of a multi-line range, omit for single lines"},
+          "start_side": {"type": "string", "enum": ["RIGHT", "LEFT"], "description": "Side of start_line, omit if it is the same as side"},
+          "side": {"type": "string", "enum": ["RIGHT", "LEFT"], "description": "RIGHT for added/unchanged lines, LEFT for deleted lines"},
+          "severity": {"type": "string", "enum": ["nit", "suggestion", "issue", "blocking", "question"]},
+          "focus_area": {"type": "string", "enum": ["style", "perf", "security", "docs", "test", "refactor"]},
+          "body": {"type": "string", "description": "The comment in GitHub markdown, may include code examples"},
+          "suggestion": {
+            "type": "object",
+            "description": "Optional one-click fix replacing lines start_line..line (or just line) of the new version of the file",
+            "properties": {
+              "original": {"type": "string", "description": "The exact current content of the replaced lines"},
+              "replacement": {"type": "string", "description": "The code replacing them, with the same indentation"}
+            },
+            "required": ["original", "replacement"],
+            "additionalProperties": false
+          }
+        },
+        "required": ["path", "line", "severity", "body"],
+        "additionalProperties": false
+      }
+    }
+  },
+  "required": ["summary", "comments"],
+  "additionalProperties": false
+}`),
+}
+
+// parseStructuredReview decodes the submit_review tool input and validates it against the schema
+func parseStructuredReview(input json.RawMessage) (*StructuredReview, error) {
+	decoder := json.NewDecoder(bytes.NewReader(input))
+	decoder.DisallowUnknownFields()
+
+	var review StructuredReview
+	if err := decoder.Decode(&review); err != nil {
+		return nil, fmt.Errorf("invalid review object: %w", err)
+	}
+
+	if err := review.validate(); err != nil {
+		return nil, fmt.Errorf("invalid review object: %w", err)
+	}
+
+	return &review, nil
+}
+
+// validate checks the constraints of the submit_review schema
+func (r *StructuredReview) validate() error {
+	if strings.TrimSpace(r.Summary) == "" {
+		return fmt.Errorf("summary is required")
+	}
+
+	for i, c := range r.Comments {
+		switch {
+		case strings.TrimSpace(c.Path) == "":
+			return fmt.Errorf("comments[%d]: path is required", i)
+		case c.Line < 1:
+			return fmt.Errorf("comments[%d]: line must be positive, got %d", i, c.Line)
+		case c.StartLine < 0:
+			return fmt.Errorf("comments[%d]: start_line must not be negative, got %d", i, c.StartLine)
+		case (c.StartSide == "" || c.StartSide == c.Side) && c.StartLine > c.Line:
+			// Ranges across sides are checked against the hunks when anchoring
+			return fmt.Errorf("comments[%d]: start_line %d must not be after line %d", i, c.StartLine, c.Line)
+		case c.Side != "" && c.Side != "RIGHT" && c.Side != "LEFT":
+			return fmt.Errorf("comments[%d]: invalid side %q", i, c.Side)
+		case c.StartSide != "" && c.StartSide != "RIGHT" && c.StartSide != "LEFT":
+			return fmt.Errorf("comments[%d]: invalid start_side %q", i, c.StartSide)
+		case strings.TrimSpace(c.Body) == "":
+			return fmt.Errorf("comments[%d]: body is required", i)
+		}
+
+		if ParseSeverity(c.Severity) == "" {
+			return fmt.Errorf("comments[%d]: invalid severity %q", i, c.Severity)
+		}
+		if c.FocusArea != "" && ParseFocusArea(c.FocusArea) == "" {
+			return fmt.Errorf("comments[%d]: invalid focus_area %q", i, c.FocusArea)
+		}
+	}
+
+	return nil
+}
+
+// toResult converts the structured review into the ReviewResult posted to GitHub
+func (r *StructuredReview) toResult() ReviewResult {
+	var comments []ReviewComment
+	for _, c := range r.Comments {
+		side := c.Side
+		if side == "" {
+			side = "RIGHT"
+		}
+
+		startLine, startSide := c.StartLine, c.StartSide
+		if startSide == side {
+			startSide = ""
+		}
+		if startLine == c.Line && startSide == "" {
+			startLine = 0
+		}
+		if startLine == 0 {
+			startSide = ""
+		}
+
+		var suggestion *Suggestion
+		if c.Suggestion != nil {
+			suggestion = &Suggestion{Original: c.Suggestion.Original, Replacement: c.Suggestion.Replacement}
+		}
+
+		severity, focusArea := ParseSeverity(c.Severity), ParseFocusArea(c.FocusArea)
+		comments = append(comments, ReviewComment{
+			Path:       strings.TrimSpace(c.Path),
+			Line:       c.Line,
+			StartLine:  startLine,
+			StartSide:  startSide,
+			Side:       side,
+			Body:       fmt.Sprintf("%s\n\n%s", categoryLabel(severity, focusArea), strings.TrimSpace(c.Body)),
+			Severity:   severity,
+			FocusArea:  focusArea,
+			Suggestion: suggestion,
+		})
+	}
+
+	return ReviewResult{
+		Summary:  formatSummary(strings.TrimSpace(r.Summary), strings.TrimSpace(r.Poem)),
+		Comments: comments,
+	}
+}
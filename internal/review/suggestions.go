@@ -0,0 +1,90 @@
+package review
+
+import (
+	"fmt"
+	"log"
+	"strings"
+)
+
+// Suggestion is a replacement for the lines a comment covers, posted as a GitHub
+// suggestion block so it can be committed with one click
+type Suggestion struct {
+	Original    string // the lines being replaced, as the model saw them
+	Replacement string
+}
+
+// RenderSuggestions appends the suggestions of the comments to their bodies. A suggestion
+// is only rendered as a committable ```suggestion block when its original lines match the
+// file at the head commit, as returned by contents; otherwise it is shown as plain code.
+func RenderSuggestions(result ReviewResult, contents func(path string) (string, error)) ReviewResult {
+	files := make(map[string][]string)
+	comments := make([]ReviewComment, 0, len(result.Comments))
+
+	for _, c := range result.Comments {
+		if c.Suggestion == nil {
+			comments = append(comments, c)
+			continue
+		}
+
+		lines, ok := files[c.Path]
+		if !ok {
+			content, err := contents(c.Path)
+			if err != nil {
+				log.Printf("Cannot verify suggestion on %s: %v", c.Path, err)
+			}
+			lines = splitLines(content)
+			files[c.Path] = lines
+		}
+
+		// Suggestions replace lines of the new version, so they can't cover deleted lines
+		if c.Side == "RIGHT" && (c.StartSide == "" || c.StartSide == "RIGHT") && suggestionMatches(lines, c.firstLine(), c.Line, c.Suggestion.Original) {
+			c.Body += fmt.Sprintf("\n\n```suggestion\n%s\n```", strings.TrimRight(c.Suggestion.Replacement, "\n"))
+		} else {
+			log.Printf("Suggestion on %s:%d doesn't match the file, rendering it as plain code", c.Path, c.Line)
+			c = plainSuggestion(c)
+		}
+		c.Suggestion = nil
+
+		comments = append(comments, c)
+	}
+
+	result.Comments = comments
+	return result
+}
+
+// plainSuggestion appends the suggestion of a comment to its body as plain code, which
+// can't be committed, and drops it from the comment
+func plainSuggestion(c ReviewComment) ReviewComment {
+	c.Body += fmt.Sprintf("\n\n**Suggested change:**\n```\n%s\n```", strings.TrimRight(c.Suggestion.Replacement, "\n"))
+	c.Suggestion = nil
+	return c
+}
+
+// suggestionMatches reports whether lines start..end (1-based) of a file are the original
+// lines of a suggestion, ignoring trailing whitespace
+func suggestionMatches(lines []string, start, end int, original string) bool {
+	if start < 1 || end > len(lines) || start > end {
+		return false
+	}
+
+	want := splitLines(original)
+	got := lines[start-1 : end]
+	if len(want) != len(got) {
+		return false
+	}
+	for i := range want {
+		if strings.TrimRight(want[i], " \t") != strings.TrimRight(got[i], " \t") {
+			return false
+		}
+	}
+	return true
+}
+
+// splitLines splits text into lines, tolerating CRLF line endings and a trailing newline
+func splitLines(text string) []string {
+	text = strings.TrimSuffix(strings.ReplaceAll(text, "\r\n", "\n"), "\n")
+	if text == "" {
+		return nil
+	}
+	return strings.Split(text, "\n")
+}
@@ -1,10 +1,34 @@
 package review
 
+// ReviewHeader is the heading of every review summary Cyclone posts, used to
+// recognize Cyclone's own reviews on a PR
+const ReviewHeader = "## 🌪️ Cyclone AI Code Review"
+
 type ReviewComment struct {
-	Path string
-	Line int
-	Body string
-	Side string
+	Path       string
+	Line       int
+	StartLine  int    // first line of a multi-line comment, 0 for a single line
+	StartSide  string // side of StartLine, "" for the same side as Line
+	Body       string
+	Side       string
+	Severity   Severity    // "" if the model gave none
+	FocusArea  FocusArea   // "" if the comment has no focus area
+	Suggestion *Suggestion // replacement of lines StartLine..Line, nil if none
+}
+
+// firstLine returns the first line a comment covers
+func (c ReviewComment) firstLine() int {
+	if c.StartLine > 0 {
+		return c.StartLine
+	}
+	return c.Line
+}
+
+// ThreadComment is one comment of a review thread Cyclone takes part in
+type ThreadComment struct {
+	Author      string
+	Body        string
+	FromCyclone bool
 }
 
 type ReviewResult struct {
@@ -17,3 +41,13 @@ type PRSizeCheck struct {
 	WarningMessage string
 	SkipMessage    string
 }
+
+// CompareResult holds the diff between two commits of a PR
+type CompareResult struct {
+	Status       string // ahead, behind, identical or diverged
+	TotalCommits int
+	Files        int
+	Additions    int
+	Deletions    int
+	Diff         *PRDiff
+}